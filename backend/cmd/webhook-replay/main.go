@@ -0,0 +1,87 @@
+// Command webhook-replay signs payment webhook fixtures and posts them to a
+// running server, standing in for the gateway during local testing.
+//
+//	go run ./cmd/webhook-replay -reference mock_2_1 payments/testdata/webhooks/out-of-order
+//
+// Fixtures are JSON events; "{{reference}}" is replaced with -reference,
+// in event IDs too, so each payment gets its own events instead of
+// duplicates of an earlier run. Directories are replayed in file name order.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"shopping-cart/payments"
+)
+
+func main() {
+	url := flag.String("url", "http://localhost:8080/payments/webhook", "webhook endpoint")
+	secret := flag.String("secret", os.Getenv("PAYMENT_WEBHOOK_SECRET"), "signing secret")
+	reference := flag.String("reference", "", "payment reference to put in the fixtures")
+	flag.Parse()
+
+	if *secret == "" {
+		log.Fatal("a signing secret is required (-secret or PAYMENT_WEBHOOK_SECRET)")
+	}
+	if flag.NArg() == 0 {
+		log.Fatal("usage: webhook-replay [flags] fixture-file-or-dir...")
+	}
+
+	files, err := fixtureFiles(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		body = bytes.ReplaceAll(body, []byte("{{reference}}"), []byte(*reference))
+
+		req, err := http.NewRequest(http.MethodPost, *url, bytes.NewReader(body))
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(payments.SignatureHeader, payments.Sign(*secret, body))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		fmt.Printf("%s -> %d %s\n", filepath.Base(file), resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+}
+
+// fixtureFiles expands directories into their JSON files, sorted by name
+func fixtureFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
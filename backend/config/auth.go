@@ -0,0 +1,46 @@
+package config
+
+import "time"
+
+// RequireAdmin2FA makes admin accounts set up two-factor authentication
+// before they can use anything else
+var RequireAdmin2FA = getEnv("REQUIRE_ADMIN_2FA", "false") == "true"
+
+// TOTPIssuer names the shop in authenticator apps
+var TOTPIssuer = getEnv("TOTP_ISSUER", "abcdeVentures")
+
+// LoginChallengeTTL is how long a user has to enter their two-factor code
+// after the password
+var LoginChallengeTTL = getDuration("LOGIN_CHALLENGE_TTL", 5*time.Minute)
+
+// LoginAttemptStore is where failed logins are counted: "memory" for a
+// single node, or "db" to share the counts between nodes
+var LoginAttemptStore = getEnv("LOGIN_ATTEMPT_STORE", "memory")
+
+// An account is locked for LoginLockoutDuration after LoginLockoutThreshold
+// failed logins within LoginFailureWindow. Each failure also makes the next
+// attempt wait, twice as long as the one before. Addresses get more tries,
+// since several users can share one.
+var (
+	LoginLockoutThreshold   = getInt("LOGIN_LOCKOUT_THRESHOLD", 5)
+	LoginLockoutDuration    = getDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	LoginFailureWindow      = getDuration("LOGIN_FAILURE_WINDOW", time.Hour)
+	LoginIPLockoutThreshold = getInt("LOGIN_IP_LOCKOUT_THRESHOLD", 20)
+)
+
+// TrustedProxies are the addresses or CIDR ranges of the proxies in front of
+// the server. X-Forwarded-For is only believed from these, so by default the
+// client address used for throttling is the one that connected.
+var TrustedProxies = getList("TRUSTED_PROXIES")
+
+// ImpersonationTTL is how long an admin can act as a customer with one
+// impersonation token
+var ImpersonationTTL = getDuration("IMPERSONATION_TTL", 30*time.Minute)
+
+// ErasureCoolingOff is how long a customer can change their mind after
+// asking for their account to be erased
+var ErasureCoolingOff = getDuration("ERASURE_COOLING_OFF", 14*24*time.Hour)
+
+// APIKeyRotationGrace is how long a rotated API key keeps working, so
+// integrations can switch to the new key without downtime
+var APIKeyRotationGrace = getDuration("API_KEY_ROTATION_GRACE", 24*time.Hour)
@@ -8,7 +8,8 @@ import (
 var DB *gorm.DB
 
 func Connect() {
-	db, err := gorm.Open(sqlite.Open("shop.db"), &gorm.Config{})
+	// busy_timeout makes concurrent writers wait for the lock instead of failing
+	db, err := gorm.Open(sqlite.Open("shop.db?_pragma=busy_timeout(5000)"), &gorm.Config{})
 	if err != nil {
 		panic(err)   // <-- temporarily show real error
 	}
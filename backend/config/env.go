@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getEnv returns the environment variable or fallback when it is unset
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// getDuration parses the environment variable as a duration, falling back
+// when it is unset or invalid
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(getEnv(key, "")); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// getInt parses the environment variable as a positive integer, falling
+// back when it is unset or invalid
+func getInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(getEnv(key, "")); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
+
+// getBool parses the environment variable as true or false, falling back
+// when it is unset or invalid
+func getBool(key string, fallback bool) bool {
+	if b, err := strconv.ParseBool(getEnv(key, "")); err == nil {
+		return b
+	}
+	return fallback
+}
+
+// getList splits the environment variable on commas, dropping empty
+// entries. It is nil when the variable is unset or empty.
+func getList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
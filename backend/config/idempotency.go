@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// IdempotencyWindow is how long a response is kept for replay to requests
+// that repeat its Idempotency-Key, e.g. "24h"
+var IdempotencyWindow = getDuration("IDEMPOTENCY_WINDOW", 24*time.Hour)
+
+// IdempotencyLease is how long a request holds its Idempotency-Key while it
+// runs. A key still unfinished after that, e.g. because the server stopped,
+// can be taken over by a retry.
+var IdempotencyLease = getDuration("IDEMPOTENCY_LEASE", time.Minute)
+
+// IdempotencyMaxBody is the largest request body, in bytes, accepted with an
+// Idempotency-Key
+var IdempotencyMaxBody = getInt("IDEMPOTENCY_MAX_BODY", 1<<20)
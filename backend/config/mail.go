@@ -0,0 +1,29 @@
+package config
+
+import "time"
+
+// Mail transport. Mail goes out over SMTP when SMTPAddr is set, otherwise
+// it is appended to MailFile, or written to the log when that is unset too.
+var (
+	SMTPAddr     = getEnv("SMTP_ADDR", "") // host:port
+	SMTPUsername = getEnv("SMTP_USERNAME", "")
+	SMTPPassword = getEnv("SMTP_PASSWORD", "")
+	MailFrom     = getEnv("MAIL_FROM", "abcdeVentures <no-reply@abcdeventures.example>")
+	MailFile     = getEnv("MAIL_FILE", "")
+)
+
+// AppURL is the storefront address used for links in emails
+var AppURL = getEnv("APP_URL", "https://abcde-ventures-nine.vercel.app")
+
+// PasswordResetTTL is how long a password reset link stays valid
+var PasswordResetTTL = getDuration("PASSWORD_RESET_TTL", time.Hour)
+
+// EmailVerificationTTL is how long an email verification link stays valid
+var EmailVerificationTTL = getDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour)
+
+// Verification emails can be resent once per VerificationResendInterval,
+// and at most VerificationResendLimit times a day
+var (
+	VerificationResendInterval = getDuration("VERIFICATION_RESEND_INTERVAL", time.Minute)
+	VerificationResendLimit    = 5
+)
@@ -0,0 +1,14 @@
+package config
+
+// Password policy for new passwords. PasswordBlocklistFile names a list of
+// common or breached passwords, one per line as plain text or SHA-1 hex,
+// used on top of the built-in list.
+var (
+	PasswordMinLength      = getInt("PASSWORD_MIN_LENGTH", 8)
+	PasswordRequireUpper   = getBool("PASSWORD_REQUIRE_UPPER", true)
+	PasswordRequireLower   = getBool("PASSWORD_REQUIRE_LOWER", true)
+	PasswordRequireDigit   = getBool("PASSWORD_REQUIRE_DIGIT", true)
+	PasswordRequireSpecial = getBool("PASSWORD_REQUIRE_SPECIAL", true)
+	PasswordRejectUsername = getBool("PASSWORD_REJECT_USERNAME", true)
+	PasswordBlocklistFile  = getEnv("PASSWORD_BLOCKLIST_FILE", "")
+)
@@ -0,0 +1,5 @@
+package config
+
+// PaymentWebhookSecret signs payment gateway webhooks. Webhooks are
+// rejected while it is unset.
+var PaymentWebhookSecret = getEnv("PAYMENT_WEBHOOK_SECRET", "")
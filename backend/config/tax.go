@@ -0,0 +1,8 @@
+package config
+
+// TaxPricingMode is "exclusive" when item prices exclude tax and tax is added
+// at checkout, or "inclusive" when item prices already contain tax
+var TaxPricingMode = getEnv("TAX_PRICING_MODE", "exclusive")
+
+// TaxDefaultRegion is used to tax carts that have no region of their own
+var TaxDefaultRegion = getEnv("TAX_DEFAULT_REGION", "")
@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// addressInput is the editable part of an address
+type addressInput struct {
+	Name       string
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+	Phone      string
+	IsDefault  bool
+}
+
+// validateAddress checks that an address has what a carrier needs
+func validateAddress(input addressInput) string {
+	if input.Name == "" {
+		return "Name is required"
+	}
+	if input.Line1 == "" {
+		return "Address line 1 is required"
+	}
+	if input.City == "" {
+		return "City is required"
+	}
+	if len(input.Country) != 2 {
+		return "Country must be a 2-letter code"
+	}
+	for _, field := range []string{input.Name, input.Line1, input.Line2, input.City, input.Region, input.PostalCode, input.Phone} {
+		if len(field) > 100 {
+			return "Address fields must be less than 100 characters"
+		}
+	}
+	return ""
+}
+
+// bindAddress reads and validates an address from the request body
+func bindAddress(c *gin.Context) (addressInput, bool) {
+	var input addressInput
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return input, false
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	input.Line1 = strings.TrimSpace(input.Line1)
+	input.Line2 = strings.TrimSpace(input.Line2)
+	input.City = strings.TrimSpace(input.City)
+	input.Region = strings.ToUpper(strings.TrimSpace(input.Region))
+	input.PostalCode = strings.TrimSpace(input.PostalCode)
+	input.Country = strings.ToUpper(strings.TrimSpace(input.Country))
+	input.Phone = strings.TrimSpace(input.Phone)
+
+	if errMsg := validateAddress(input); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return input, false
+	}
+	return input, true
+}
+
+// findUserAddress loads an address belonging to the user
+func findUserAddress(db *gorm.DB, userID uint, id uint64) (models.Address, error) {
+	var address models.Address
+	err := db.Where("id = ? AND user_id = ?", id, userID).First(&address).Error
+	return address, err
+}
+
+// findShippingAddress loads the given address of the user, or their default
+// address when id is 0
+func findShippingAddress(db *gorm.DB, userID uint, id uint) (models.Address, error) {
+	if id != 0 {
+		return findUserAddress(db, userID, uint64(id))
+	}
+	var address models.Address
+	err := db.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error
+	return address, err
+}
+
+// saveAddress saves the address, keeping exactly one default per user
+func saveAddress(address *models.Address) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		// The first address is always the default
+		var count int64
+		tx.Model(&models.Address{}).Where("user_id = ? AND id <> ?", address.UserID, address.ID).Count(&count)
+		if count == 0 {
+			address.IsDefault = true
+		}
+		if address.IsDefault {
+			if err := tx.Model(&models.Address{}).Where("user_id = ? AND id <> ?", address.UserID, address.ID).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(address).Error
+	})
+}
+
+func ListAddresses(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var addresses []models.Address
+	if err := config.DB.Where("user_id = ?", user.ID).Order("is_default desc, id").Find(&addresses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch addresses"})
+		return
+	}
+	c.JSON(http.StatusOK, addresses)
+}
+
+func CreateAddress(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	input, ok := bindAddress(c)
+	if !ok {
+		return
+	}
+
+	address := models.Address{
+		UserID:     user.ID,
+		Name:       input.Name,
+		Line1:      input.Line1,
+		Line2:      input.Line2,
+		City:       input.City,
+		Region:     input.Region,
+		PostalCode: input.PostalCode,
+		Country:    input.Country,
+		Phone:      input.Phone,
+		IsDefault:  input.IsDefault,
+	}
+	if err := saveAddress(&address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create address"})
+		return
+	}
+	c.JSON(http.StatusCreated, address)
+}
+
+func UpdateAddress(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+	address, err := findUserAddress(config.DB, user.ID, parsedID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+	input, ok := bindAddress(c)
+	if !ok {
+		return
+	}
+
+	// Orders keep their own snapshot, so editing in place is safe
+	address.Name = input.Name
+	address.Line1 = input.Line1
+	address.Line2 = input.Line2
+	address.City = input.City
+	address.Region = input.Region
+	address.PostalCode = input.PostalCode
+	address.Country = input.Country
+	address.Phone = input.Phone
+	address.IsDefault = address.IsDefault || input.IsDefault
+
+	if err := saveAddress(&address); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update address"})
+		return
+	}
+	c.JSON(http.StatusOK, address)
+}
+
+func DeleteAddress(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+		return
+	}
+	address, err := findUserAddress(config.DB, user.ID, parsedID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Address not found"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&address).Error; err != nil {
+			return err
+		}
+		// Promote the oldest remaining address to default
+		if address.IsDefault {
+			var next models.Address
+			if err := tx.Where("user_id = ?", user.ID).Order("id").First(&next).Error; err == nil {
+				return tx.Model(&next).Update("is_default", true).Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete address"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Address deleted"})
+}
@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/middleware"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// adminUserResponse is a user as admins see it
+type adminUserResponse struct {
+	profileResponse
+	Disabled bool `json:"disabled"`
+	Deleted  bool `json:"deleted"`
+}
+
+func newAdminUserResponse(user models.User) adminUserResponse {
+	return adminUserResponse{
+		profileResponse: newProfileResponse(user),
+		Disabled:        user.Disabled,
+		Deleted:         user.AnonymizedAt != nil,
+	}
+}
+
+// withoutSecrets leaves password hashes and session tokens out when users
+// are preloaded into a response
+func withoutSecrets(db *gorm.DB) *gorm.DB {
+	return db.Omit("password", "token")
+}
+
+// openOrderStatuses are the statuses of orders that are still being handled
+var openOrderStatuses = []string{"pending", "processing", "partially_shipped"}
+
+// anonymizeUser deletes everything personal about a user but keeps the row,
+// so their orders and invoices still add up. The account can no longer be
+// used. Reviews stay, without the author's name.
+func anonymizeUser(tx *gorm.DB, user *models.User) error {
+	now := time.Now()
+	// Updates writes the new values back into user, so keep the old key
+	lockoutKey := accountKey(user.Username)
+	if err := tx.Model(user).Updates(map[string]any{
+		"username":          fmt.Sprintf("deleted_%d", user.ID),
+		"display_name":      "",
+		"email":             nil,
+		"email_verified_at": nil,
+		"password":          "",
+		"token":             "",
+		"disabled":          true,
+		"anonymized_at":     now,
+		"pref_newsletter":   false,
+	}).Error; err != nil {
+		return err
+	}
+
+	var cartIDs, wishlistIDs []uint
+	if err := tx.Model(&models.Cart{}).Where("user_id = ?", user.ID).Pluck("id", &cartIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Wishlist{}).Where("user_id = ?", user.ID).Pluck("id", &wishlistIDs).Error; err != nil {
+		return err
+	}
+	deletes := []struct {
+		model any
+		where string
+		arg   any
+	}{
+		{&models.CartItem{}, "cart_id IN ?", cartIDs},
+		{&models.WishlistItem{}, "wishlist_id IN ?", wishlistIDs},
+		{&models.Wishlist{}, "user_id = ?", user.ID},
+		{&models.Address{}, "user_id = ?", user.ID},
+		{&models.TwoFactor{}, "user_id = ?", user.ID},
+		{&models.RecoveryCode{}, "user_id = ?", user.ID},
+		{&models.LoginChallenge{}, "user_id = ?", user.ID},
+		{&models.PasswordResetToken{}, "user_id = ?", user.ID},
+		{&models.EmailVerificationToken{}, "user_id = ?", user.ID},
+		{&models.IdempotencyKey{}, "user_id = ?", user.ID},
+		{&models.ImpersonationSession{}, "user_id = ?", user.ID},
+	}
+	for _, d := range deletes {
+		if err := tx.Where(d.where, d.arg).Delete(d.model).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&models.Review{}).Where("user_id = ?", user.ID).Update("author", "Deleted user").Error; err != nil {
+		return err
+	}
+
+	accountLimiter.Reset(lockoutKey)
+	return nil
+}
+
+// findUser loads the user named in the URL for an admin. It refuses
+// non-admins and, with notSelf, the admin's own account.
+func findUser(c *gin.Context, notSelf bool) (models.User, models.User, bool) {
+	var user models.User
+	admin := c.MustGet("user").(models.User)
+	if !admin.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return admin, user, false
+	}
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return admin, user, false
+	}
+	if err := config.DB.First(&user, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return admin, user, false
+	}
+	if notSelf && user.ID == admin.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot do this to your own account"})
+		return admin, user, false
+	}
+	return admin, user, true
+}
+
+// ListUsers - lets admin page through users, 20 at a time by default.
+// ?q= searches usernames and emails, ?role= is admin or customer, and
+// ?status= is active, disabled or deleted.
+func ListUsers(c *gin.Context) {
+	// Check if admin
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	page = max(page, 1)
+	perPage = min(max(perPage, 1), 100)
+
+	query := config.DB.Model(&models.User{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(username) LIKE ? OR email LIKE ?", like, like)
+	}
+	switch c.Query("role") {
+	case "admin":
+		query = query.Where("admin = ?", true)
+	case "customer":
+		query = query.Where("admin = ?", false)
+	}
+	switch c.Query("status") {
+	case "active":
+		query = query.Where("disabled = ?", false)
+	case "disabled":
+		query = query.Where("disabled = ? AND anonymized_at IS NULL", true)
+	case "deleted":
+		query = query.Where("anonymized_at IS NOT NULL")
+	}
+
+	var total int64
+	var users []models.User
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+	if err := query.Order("id").Limit(perPage).Offset((page - 1) * perPage).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		return
+	}
+
+	// Don't return passwords or tokens
+	results := make([]adminUserResponse, 0, len(users))
+	for _, u := range users {
+		results = append(results, newAdminUserResponse(u))
+	}
+	c.JSON(http.StatusOK, gin.H{"users": results, "page": page, "per_page": perPage, "total": total})
+}
+
+// GetUser - lets admin look at one user, with their order count and
+// whether logins are locked
+func GetUser(c *gin.Context) {
+	_, user, ok := findUser(c, false)
+	if !ok {
+		return
+	}
+
+	var orders int64
+	config.DB.Model(&models.Order{}).Where("user_id = ?", user.ID).Count(&orders)
+	_, locked, _ := accountLimiter.Check(accountKey(user.Username), time.Now())
+	c.JSON(http.StatusOK, gin.H{"user": newAdminUserResponse(user), "order_count": orders, "login_locked": locked})
+}
+
+// DisableUser - lets admin stop a user from logging in. Their sessions end
+// at once.
+func DisableUser(c *gin.Context) {
+	admin, user, ok := findUser(c, true)
+	if !ok {
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := bindOptionalJSON(c, &body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]any{"disabled": true, "token": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ImpersonationSession{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable user"})
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "account.disabled", accountKey(user.Username), strings.TrimSpace(body.Reason))
+	c.JSON(http.StatusOK, gin.H{"message": "User disabled", "user": newAdminUserResponse(user)})
+}
+
+// EnableUser - lets admin allow a disabled user to log in again
+func EnableUser(c *gin.Context) {
+	admin, user, ok := findUser(c, true)
+	if !ok {
+		return
+	}
+	if user.AnonymizedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Deleted accounts cannot be enabled"})
+		return
+	}
+
+	if err := config.DB.Model(&user).Update("disabled", false).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable user"})
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "account.enabled", accountKey(user.Username), "")
+	c.JSON(http.StatusOK, gin.H{"message": "User enabled", "user": newAdminUserResponse(user)})
+}
+
+// DeleteUser - lets admin delete a user. Their personal data is removed but
+// their orders are kept, anonymized.
+func DeleteUser(c *gin.Context) {
+	admin, user, ok := findUser(c, true)
+	if !ok {
+		return
+	}
+	if user.AnonymizedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already deleted"})
+		return
+	}
+
+	var open int64
+	config.DB.Model(&models.Order{}).Where("user_id = ? AND status IN ?", user.ID, openOrderStatuses).Count(&open)
+	if open > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User has orders in progress"})
+		return
+	}
+
+	subject := accountKey(user.Username)
+	if err := config.DB.Transaction(func(tx *gorm.DB) error { return anonymizeUser(tx, &user) }); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "account.deleted", subject, "Anonymized as "+user.Username)
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// SetUserRole - lets admin give or take away admin rights
+func SetUserRole(c *gin.Context) {
+	admin, user, ok := findUser(c, true)
+	if !ok {
+		return
+	}
+	var body struct {
+		Admin *bool `json:"admin"`
+	}
+	if err := c.BindJSON(&body); err != nil || body.Admin == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "admin must be true or false"})
+		return
+	}
+	if user.AnonymizedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is deleted"})
+		return
+	}
+	if user.Admin == *body.Admin {
+		c.JSON(http.StatusOK, gin.H{"message": "Role unchanged", "user": newAdminUserResponse(user)})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("admin", *body.Admin).Error; err != nil {
+			return err
+		}
+		// A former admin's impersonations end with their rights
+		return tx.Model(&models.ImpersonationSession{}).Where("admin_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change role"})
+		return
+	}
+	role := "customer"
+	if user.Admin {
+		role = "admin"
+	}
+	recordAudit(config.DB, c, admin.Username, "role.changed", accountKey(user.Username), "Now "+role)
+	c.JSON(http.StatusOK, gin.H{"message": "Role changed", "user": newAdminUserResponse(user)})
+}
+
+// ImpersonateUser - gives admin a short-lived token that acts as the
+// customer, to reproduce problems with their cart. A reason is required
+// and everything changed with the token is audited.
+func ImpersonateUser(c *gin.Context) {
+	admin, user, ok := findUser(c, true)
+	if !ok {
+		return
+	}
+	if _, impersonating := c.Get("impersonation"); impersonating {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a customer"})
+		return
+	}
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return
+	}
+	if user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admins cannot be impersonated"})
+		return
+	}
+	if user.Disabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Disabled users cannot be impersonated"})
+		return
+	}
+	var services int64
+	config.DB.Model(&models.ServiceAccount{}).Where("user_id = ?", user.ID).Count(&services)
+	if services > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Service accounts cannot be impersonated"})
+		return
+	}
+
+	secret, _, err := newSecretToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	token := middleware.ImpersonationPrefix + secret
+	session := models.ImpersonationSession{
+		AdminID:   admin.ID,
+		UserID:    user.ID,
+		TokenHash: hashSecretToken(token),
+		Reason:    body.Reason,
+		ExpiresAt: time.Now().Add(config.ImpersonationTTL),
+	}
+	if err := config.DB.Create(&session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "impersonation.started", accountKey(user.Username), body.Reason)
+	c.JSON(http.StatusCreated, gin.H{"token": token, "expires_at": session.ExpiresAt, "user": newProfileResponse(user)})
+}
+
+// EndImpersonation - revokes the impersonation token used to call it
+func EndImpersonation(c *gin.Context) {
+	value, ok := c.Get("impersonation")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not impersonating a customer"})
+		return
+	}
+	session := value.(models.ImpersonationSession)
+	user := c.MustGet("user").(models.User)
+
+	if err := config.DB.Model(&session).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end impersonation"})
+		return
+	}
+	recordAudit(config.DB, c, session.Admin.Username, "impersonation.ended", accountKey(user.Username), "")
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation ended"})
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/http"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordAudit adds an entry to the audit trail. A failure to write it is
+// not allowed to break the request being audited.
+func recordAudit(db *gorm.DB, c *gin.Context, actor, action, subject, detail string) {
+	entry := models.AuditLog{Actor: actor, Action: action, Subject: subject, Detail: detail}
+	if c != nil {
+		entry.IP = c.ClientIP()
+	}
+	db.Create(&entry)
+}
+
+// ListAuditLogs - lets admin see the latest audit entries, optionally
+// filtered with ?action= and ?subject=
+func ListAuditLogs(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+
+	query := config.DB.Order("id desc").Limit(200)
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if subject := c.Query("subject"); subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+	var entries []models.AuditLog
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -1,15 +1,121 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"shopping-cart/config"
 	"shopping-cart/models"
+	"shopping-cart/pricing"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// pricedCart is a cart's items priced together with the coupon it carries
+type pricedCart struct {
+	Items       []models.CartItem
+	Quote       *pricing.Quote
+	Coupon      *models.Coupon
+	CouponError string
+}
+
+// findOrCreateCart returns the user's cart, creating it if needed
+func findOrCreateCart(db *gorm.DB, userID uint) (models.Cart, error) {
+	var cart models.Cart
+	err := db.Where(models.Cart{UserID: userID}).FirstOrCreate(&cart).Error
+	return cart, err
+}
+
+// priceCart loads the cart items, applies promotions and the cart's coupon,
+// then taxes the result for region. A coupon that no longer applies is
+// reported in CouponError and skipped.
+func priceCart(db *gorm.DB, cart models.Cart, userID uint, region string) (*pricedCart, error) {
+	priced := &pricedCart{}
+	if err := db.Preload("Item").Where("cart_id = ?", cart.ID).Order("id").Find(&priced.Items).Error; err != nil {
+		return nil, err
+	}
+	priced.Quote = pricing.NewQuote(priced.Items)
+
+	// Automatic promotions run first, coupons apply to what is left
+	var promotions []models.Promotion
+	if err := db.Where("disabled = ?", false).Find(&promotions).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	priced.Quote.ApplyPromotions(promotions, now)
+
+	if cart.CouponID != nil {
+		var coupon models.Coupon
+		if err := db.First(&coupon, *cart.CouponID).Error; err != nil {
+			priced.CouponError = "Coupon is no longer available"
+		} else {
+			priced.Coupon = &coupon
+			priced.CouponError = checkCouponUsage(db, coupon, userID)
+			if priced.CouponError == "" {
+				priced.CouponError = priced.Quote.ApplyCoupon(coupon, now)
+			}
+		}
+	}
+
+	// Tax is worked out last, on the discounted amounts
+	calc, err := newTaxCalculator(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := priced.Quote.ApplyTax(calc, normalizeRegion(region), config.TaxPricingMode); err != nil {
+		return nil, err
+	}
+	return priced, nil
+}
+
+// stockError reports that an item does not have enough stock for the cart
+type stockError struct {
+	Available int
+}
+
+func (e *stockError) Error() string {
+	return fmt.Sprintf("only %d in stock", e.Available)
+}
+
+// addItemToCart adds quantity of the item to the cart at its current price,
+// on top of what is already there, capped at 100 and limited by stock
+func addItemToCart(db *gorm.DB, cart models.Cart, item models.Item, quantity int) (models.CartItem, error) {
+	var cartItem models.CartItem
+	if item.Archived {
+		return cartItem, &apiError{http.StatusConflict, "Item is no longer available"}
+	}
+
+	// Check if item already in cart
+	if err := db.Where("cart_id=? AND item_id=?", cart.ID, item.ID).First(&cartItem).Error; err == nil {
+		// Item already in cart, update quantity and bring the price up to date
+		cartItem.Quantity += quantity
+		cartItem.Price = item.Price
+		// Cap quantity at 100
+		if cartItem.Quantity > 100 {
+			cartItem.Quantity = 100
+		}
+	} else {
+		// Add new item to cart with price
+		cartItem = models.CartItem{
+			CartID:   cart.ID,
+			ItemID:   item.ID,
+			Price:    item.Price,
+			Quantity: quantity,
+		}
+	}
+
+	// Check stock for items that track it
+	if item.Stock != nil && cartItem.Quantity > *item.Stock {
+		return cartItem, &stockError{Available: *item.Stock}
+	}
+
+	return cartItem, db.Save(&cartItem).Error
+}
+
 func AddToCart(c *gin.Context) {
 	user := c.MustGet("user").(models.User)
 	var body struct {
@@ -31,8 +137,8 @@ func AddToCart(c *gin.Context) {
 	}
 
 	// Get or create cart for user
-	var cart models.Cart
-	if err := config.DB.Where("user_id=?", user.ID).FirstOrCreate(&cart).Error; err != nil {
+	cart, err := findOrCreateCart(config.DB, user.ID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart"})
 		return
 	}
@@ -44,27 +150,14 @@ func AddToCart(c *gin.Context) {
 		return
 	}
 
-	// Check if item already in cart
-	var cartItem models.CartItem
-	if err := config.DB.Where("cart_id=? AND item_id=?", cart.ID, body.ItemID).First(&cartItem).Error; err == nil {
-		// Item already in cart, update quantity
-		cartItem.Quantity += body.Quantity
-		// Cap quantity at 100
-		if cartItem.Quantity > 100 {
-			cartItem.Quantity = 100
-		}
-	} else {
-		// Add new item to cart with price
-		cartItem = models.CartItem{
-			CartID:   cart.ID,
-			ItemID:   body.ItemID,
-			Price:    item.Price,
-			Quantity: body.Quantity,
+	cartItem, err := addItemToCart(config.DB, cart, item, body.Quantity)
+	if err != nil {
+		var stockErr *stockError
+		if errors.As(err, &stockErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Not enough stock", "available": stockErr.Available})
+			return
 		}
-	}
-
-	if err := config.DB.Save(&cartItem).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to cart"})
+		respondError(c, err, "Failed to add to cart")
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": "Added to cart", "cart_item": cartItem})
@@ -114,6 +207,13 @@ func UpdateCartItem(c *gin.Context) {
 		return
 	}
 
+	// Check stock for items that track it
+	var item models.Item
+	if err := config.DB.First(&item, cartItem.ItemID).Error; err == nil && item.Stock != nil && body.Quantity > *item.Stock {
+		c.JSON(http.StatusConflict, gin.H{"error": "Not enough stock", "available": *item.Stock})
+		return
+	}
+
 	cartItem.Quantity = body.Quantity
 	if err := config.DB.Save(&cartItem).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart item"})
@@ -158,6 +258,77 @@ func RemoveCartItem(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Cart item removed"})
 }
 
+// ApplyCoupon - applies a coupon code to the user's cart, or removes it when the code is empty
+func ApplyCoupon(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	cart, err := findOrCreateCart(config.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart"})
+		return
+	}
+
+	code := normalizeCouponCode(body.Code)
+	if code == "" {
+		if err := config.DB.Model(&cart).Update("coupon_id", nil).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove coupon"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Coupon removed"})
+		return
+	}
+
+	var coupon models.Coupon
+	if err := config.DB.Where("code = ?", code).First(&coupon).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	// Price the cart with the coupon before saving it
+	cart.CouponID = &coupon.ID
+	priced, err := priceCart(config.DB, cart, user.ID, cartTaxRegion(c, user.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart items"})
+		return
+	}
+	if priced.CouponError != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": priced.CouponError})
+		return
+	}
+
+	if err := config.DB.Model(&cart).Update("coupon_id", coupon.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply coupon"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Coupon applied",
+		"coupon":   coupon.Code,
+		"subtotal": priced.Quote.Subtotal,
+		"discount": priced.Quote.Discount,
+		"tax":      priced.Quote.Tax,
+		"total":    priced.Quote.Total,
+	})
+}
+
+// cartTaxRegion is the region a cart's tax is estimated for: the requested
+// region, else the user's default address, else the configured default
+func cartTaxRegion(c *gin.Context, userID uint) string {
+	if region := c.Query("region"); region != "" {
+		return region
+	}
+	if address, err := findShippingAddress(config.DB, userID, 0); err == nil {
+		return address.Snapshot().TaxRegion()
+	}
+	return config.TaxDefaultRegion
+}
+
 func ListCarts(c *gin.Context) {
 	user := c.MustGet("user").(models.User)
 	var cart models.Cart
@@ -170,19 +341,30 @@ func ListCarts(c *gin.Context) {
 		}
 	}
 
-	// Get cart items with item details included
-	var cartItems []models.CartItem
-	config.DB.Preload("Item").Where("cart_id=?", cart.ID).Find(&cartItems)
-
-	// Calculate total
-	var total float64
-	for _, item := range cartItems {
-		total += item.Price * float64(item.Quantity)
+	// Get cart items with item details included and price them
+	priced, err := priceCart(config.DB, cart, user.ID, cartTaxRegion(c, user.ID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart items"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"cart_id": cart.ID,
-		"items":   cartItems,
-		"total":   total,
-	})
+	response := gin.H{
+		"cart_id":     cart.ID,
+		"items":       priced.Items,
+		"lines":       priced.Quote.Lines,
+		"adjustments": priced.Quote.Adjustments,
+		"subtotal":    priced.Quote.Subtotal,
+		"discount":    priced.Quote.Discount,
+		"taxes":       priced.Quote.Taxes,
+		"tax":         priced.Quote.Tax,
+		"tax_mode":    priced.Quote.TaxMode,
+		"total":       priced.Quote.Total,
+	}
+	if priced.Coupon != nil {
+		response["coupon"] = priced.Coupon.Code
+	}
+	if priced.CouponError != "" {
+		response["coupon_error"] = priced.CouponError
+	}
+	c.JSON(http.StatusOK, response)
 }
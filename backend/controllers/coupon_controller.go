@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// normalizeCouponCode makes coupon codes case-insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validateCoupon checks that a coupon definition is usable
+func validateCoupon(coupon models.Coupon) string {
+	if coupon.Code == "" {
+		return "Coupon code is required"
+	}
+	if len(coupon.Code) > 32 {
+		return "Coupon code must be at most 32 characters"
+	}
+	if coupon.Type != models.CouponPercent && coupon.Type != models.CouponFixed {
+		return "Coupon type must be percent or fixed"
+	}
+	if coupon.Value <= 0 {
+		return "Coupon value must be positive"
+	}
+	if coupon.Type == models.CouponPercent && coupon.Value > 100 {
+		return "Percentage discount cannot exceed 100"
+	}
+	if coupon.MinCartValue < 0 || coupon.UsageLimit < 0 || coupon.PerUserLimit < 0 {
+		return "Coupon limits cannot be negative"
+	}
+	return ""
+}
+
+// checkCouponUsage checks the global and per-user usage limits of a coupon
+func checkCouponUsage(db *gorm.DB, coupon models.Coupon, userID uint) string {
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return "Coupon usage limit reached"
+	}
+	if coupon.PerUserLimit > 0 {
+		var used int64
+		db.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).Count(&used)
+		if used >= int64(coupon.PerUserLimit) {
+			return "You have already used this coupon"
+		}
+	}
+	return ""
+}
+
+// redeemCoupon claims one use of the coupon for an order. The used_count
+// increment is conditional so concurrent checkouts cannot exceed the limit.
+func redeemCoupon(tx *gorm.DB, coupon models.Coupon, userID, orderID uint) error {
+	result := tx.Model(&models.Coupon{}).
+		Where("id = ? AND (usage_limit = 0 OR used_count < usage_limit)", coupon.ID).
+		UpdateColumn("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &apiError{http.StatusConflict, "Coupon usage limit reached"}
+	}
+
+	// The increment above holds the write lock, so this count is stable
+	if coupon.PerUserLimit > 0 {
+		var used int64
+		if err := tx.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).Count(&used).Error; err != nil {
+			return err
+		}
+		if used >= int64(coupon.PerUserLimit) {
+			return &apiError{http.StatusConflict, "You have already used this coupon"}
+		}
+	}
+
+	return tx.Create(&models.CouponRedemption{CouponID: coupon.ID, UserID: userID, OrderID: orderID}).Error
+}
+
+func CreateCoupon(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var coupon models.Coupon
+	if err := c.BindJSON(&coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	coupon.ID = 0
+	coupon.UsedCount = 0
+	coupon.Code = normalizeCouponCode(coupon.Code)
+
+	if errMsg := validateCoupon(coupon); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	var existing models.Coupon
+	if err := config.DB.Where("code = ?", coupon.Code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coupon code already exists"})
+		return
+	}
+
+	if err := config.DB.Create(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+		return
+	}
+	c.JSON(http.StatusCreated, coupon)
+}
+
+func ListCoupons(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var coupons []models.Coupon
+	config.DB.Order("id").Find(&coupons)
+	c.JSON(http.StatusOK, coupons)
+}
+
+func DeleteCoupon(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coupon ID"})
+		return
+	}
+
+	// Detach from carts so they don't point at a missing coupon
+	config.DB.Model(&models.Cart{}).Where("coupon_id = ?", parsedID).Update("coupon_id", nil)
+	if err := config.DB.Delete(&models.Coupon{}, parsedID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted"})
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/mail"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// normalizeEmail trims and lowercases an email address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateEmail checks that email is a bare address such as name@example.com
+func validateEmail(email string) string {
+	if email == "" {
+		return "Email is required"
+	}
+	if len(email) > 254 {
+		return "Email must be less than 254 characters"
+	}
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@"):], ".") {
+		return "Email address is not valid"
+	}
+	return ""
+}
+
+// emailTaken reports whether another user already has the email address
+func emailTaken(db *gorm.DB, email string, userID uint) bool {
+	var count int64
+	db.Model(&models.User{}).Where("email = ? AND id <> ?", email, userID).Count(&count)
+	return count > 0
+}
+
+// sendVerificationEmail mails the user a link that verifies their current
+// email address
+func sendVerificationEmail(db *gorm.DB, user models.User) error {
+	token, hash, err := newSecretToken()
+	if err != nil {
+		return err
+	}
+	if err := db.Create(&models.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     *user.Email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(config.EmailVerificationTTL),
+	}).Error; err != nil {
+		return err
+	}
+
+	link := config.AppURL + "/verify-email?token=" + url.QueryEscape(token)
+	return mail.Default.Send(mail.Message{
+		To:      *user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm this is your email address by opening this link:\n\n"+
+			"%s\n\n"+
+			"The link expires in %d hours. You need a verified address to place orders.\n",
+			user.Username, link, int(config.EmailVerificationTTL.Hours())),
+	})
+}
+
+// VerifyEmail - marks the user's email as verified with a token from a
+// verification link
+func VerifyEmail(c *gin.Context) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if body.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+
+	var verification models.EmailVerificationToken
+	if err := config.DB.Where("token_hash = ? AND expires_at > ?", hashSecretToken(body.Token), time.Now()).First(&verification).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+
+	// A link for an address the user has since changed does not count
+	var user models.User
+	if err := config.DB.First(&user, verification.UserID).Error; err != nil || user.Email == nil || *user.Email != verification.Email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if user.EmailVerifiedAt == nil {
+			if err := tx.Model(&user).Update("email_verified_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.EmailVerificationToken{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification - mails a new verification link. Accounts created
+// before emails were collected can pass `email` to add one.
+func ResendVerification(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if user.EmailVerifiedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already verified"})
+		return
+	}
+
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := bindOptionalJSON(c, &body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if user.Email == nil {
+		email := normalizeEmail(body.Email)
+		if errMsg := validateEmail(email); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		if emailTaken(config.DB, email, user.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
+		if err := config.DB.Model(&user).Update("email", email).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save email"})
+			return
+		}
+		user.Email = &email
+	}
+
+	// Throttle by the links already sent
+	var recent []models.EmailVerificationToken
+	config.DB.Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-24*time.Hour)).Order("created_at desc").Find(&recent)
+	if len(recent) > 0 {
+		if wait := config.VerificationResendInterval - time.Since(recent[0].CreatedAt); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another verification email"})
+			return
+		}
+	}
+	if len(recent) >= config.VerificationResendLimit {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(recent[len(recent)-1].CreatedAt.Add(24*time.Hour)).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many verification emails requested, try again tomorrow"})
+		return
+	}
+
+	if err := sendVerificationEmail(config.DB, user); err != nil {
+		log.Printf("verification mail for user %d: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
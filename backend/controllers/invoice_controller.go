@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/invoice"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// invoiceSnapshot copies everything the invoice shows from the order
+func invoiceSnapshot(order models.Order) models.Invoice {
+	inv := models.Invoice{
+		OrderID:         order.ID,
+		IssuedAt:        time.Now(),
+		BillingAddress:  order.BillingAddress,
+		ShippingAddress: order.ShippingAddress,
+		Subtotal:        order.Subtotal,
+		Discount:        order.Discount,
+		ShippingMethod:  order.ShippingMethod,
+		Shipping:        order.ShippingCost,
+		Tax:             order.Tax,
+		TaxMode:         order.TaxMode,
+		Total:           order.Total,
+	}
+	if order.Number != nil {
+		inv.OrderNumber = *order.Number
+	}
+	if order.User != nil {
+		inv.Customer = order.User.Username
+	}
+	for _, line := range order.Items {
+		inv.Lines = append(inv.Lines, models.InvoiceLine{
+			Description: line.Name,
+			Quantity:    line.Quantity,
+			UnitPrice:   line.Price,
+			Discount:    line.Discount,
+			Tax:         line.Tax,
+			Total:       line.Total,
+		})
+	}
+	for _, adj := range order.Adjustments {
+		description := adj.Description
+		if adj.Code != "" {
+			description = adj.Code + ": " + adj.Description
+		}
+		inv.Adjustments = append(inv.Adjustments, models.InvoiceCharge{Description: description, Amount: adj.Amount})
+	}
+	for _, tax := range order.Taxes {
+		inv.Taxes = append(inv.Taxes, models.InvoiceCharge{Description: tax.Name, Amount: tax.Amount})
+	}
+	return inv
+}
+
+// issueInvoiceAttempts bounds the retries when concurrent invoices race
+// for the same number
+const issueInvoiceAttempts = 5
+
+// issueInvoice returns the order's invoice, issuing it on first use with the
+// next invoice number. When another request takes the number first, or
+// invoices the same order, the unique index refuses the row and it tries again.
+func issueInvoice(order models.Order) (models.Invoice, error) {
+	var inv models.Invoice
+	var err error
+	for attempt := 0; attempt < issueInvoiceAttempts; attempt++ {
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("order_id = ?", order.ID).First(&inv).Error; err == nil {
+				return nil
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			var seq uint = 1
+			var last models.Invoice
+			if err := tx.Order("seq desc").First(&last).Error; err == nil {
+				seq = last.Seq + 1
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			inv = invoiceSnapshot(order)
+			inv.Seq = seq
+			inv.Number = models.InvoiceNumber(seq)
+			return tx.Create(&inv).Error
+		})
+		if !isUniqueViolation(err) {
+			break
+		}
+	}
+	return inv, err
+}
+
+// GetInvoice - returns the invoice for an order as a PDF, or as HTML with ?format=html
+func GetInvoice(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var order models.Order
+	query := config.DB.Preload("User").Preload("Items").Preload("Adjustments").Preload("Taxes")
+	if err := orderByParam(c, query, user).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	// Orders are invoiced once the payment goes through; an invoice that
+	// was already issued stays available whatever happens to the order
+	var existing int64
+	if err := config.DB.Model(&models.Invoice{}).Where("order_id = ?", order.ID).Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoice"})
+		return
+	}
+	if existing == 0 && (order.Status == "pending" || order.Status == "failed" || order.Status == "cancelled") {
+		c.JSON(http.StatusConflict, gin.H{"error": "Order has not been paid"})
+		return
+	}
+
+	inv, err := issueInvoice(order)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue invoice"})
+		return
+	}
+
+	if c.Query("format") == "html" {
+		page, err := invoice.HTML(inv)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render invoice"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="`+inv.Number+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", invoice.PDF(inv))
+}
@@ -38,6 +38,20 @@ func CreateItem(c *gin.Context) {
 		return
 	}
 
+	// Validate weight and stock
+	if item.Weight < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Weight cannot be negative"})
+		return
+	}
+	if item.Stock != nil && *item.Stock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stock cannot be negative"})
+		return
+	}
+
+	// Ratings come from reviews only
+	item.RatingAverage = 0
+	item.RatingCount = 0
+
 	if err := config.DB.Create(&item).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create item"})
 		return
@@ -46,8 +60,15 @@ func CreateItem(c *gin.Context) {
 }
 
 func ListItems(c *gin.Context) {
+	query := config.DB.Where("archived = ?", false)
+
+	// Best rated first; items without reviews go last
+	if c.Query("sort") == "rating" {
+		query = query.Order("rating_average desc, rating_count desc, id")
+	}
+
 	var items []models.Item
-	config.DB.Find(&items)
+	query.Find(&items)
 	c.JSON(http.StatusOK, items)
 }
 
@@ -91,6 +112,32 @@ func UpdateItem(c *gin.Context) {
 		item.Name = updateData.Name
 	}
 
+	// Update category and tax class if provided
+	if updateData.Category != "" {
+		item.Category = updateData.Category
+	}
+	if updateData.TaxClass != "" {
+		item.TaxClass = updateData.TaxClass
+	}
+
+	// Validate weight if provided
+	if updateData.Weight != 0 {
+		if updateData.Weight < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Weight cannot be negative"})
+			return
+		}
+		item.Weight = updateData.Weight
+	}
+
+	// Validate stock if provided
+	if updateData.Stock != nil {
+		if *updateData.Stock < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Stock cannot be negative"})
+			return
+		}
+		item.Stock = updateData.Stock
+	}
+
 	// Validate price if provided
 	if updateData.Price != 0 {
 		if updateData.Price < 0 {
@@ -122,6 +169,19 @@ func DeleteItem(c *gin.Context) {
 		return
 	}
 
+	// Items that were ordered are archived instead, so order history and
+	// reorders can still refer to them
+	var ordered int64
+	config.DB.Model(&models.OrderItem{}).Where("item_id = ?", parsedID).Count(&ordered)
+	if ordered > 0 {
+		if err := config.DB.Model(&models.Item{}).Where("id = ?", parsedID).Update("archived", true).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive item"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Item archived"})
+		return
+	}
+
 	if err := config.DB.Delete(&models.Item{}, parsedID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
 		return
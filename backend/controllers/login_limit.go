@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/lockout"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Failed logins slow down and then lock the account, and separately the
+// address they come from
+var (
+	accountLimiter = &lockout.Limiter{
+		Threshold:    config.LoginLockoutThreshold,
+		Window:       config.LoginFailureWindow,
+		BaseDelay:    time.Second,
+		MaxDelay:     30 * time.Second,
+		LockDuration: config.LoginLockoutDuration,
+	}
+	ipLimiter = &lockout.Limiter{
+		Threshold:    config.LoginIPLockoutThreshold,
+		Window:       config.LoginFailureWindow,
+		LockDuration: config.LoginLockoutDuration,
+	}
+)
+
+func accountKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLoginLimits refuses the login with 429 while the account or the
+// client's address has to wait. Unknown usernames are limited the same way
+// as real ones so the answer does not reveal which exist.
+func checkLoginLimits(c *gin.Context, username string) bool {
+	now := time.Now()
+	accountWait, accountLocked, err := accountLimiter.Check(accountKey(username), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+	ipWait, ipLocked, err := ipLimiter.Check(ipKey(c.ClientIP()), now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+
+	wait := max(accountWait, ipWait)
+	if wait <= 0 {
+		return true
+	}
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	if accountLocked || ipLocked {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed logins, login is locked for now"})
+	} else {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("Too many failed logins, try again in %ds", seconds)})
+	}
+	return false
+}
+
+// loginFailed counts a failed login and audits any lock it causes
+func loginFailed(c *gin.Context, username string) {
+	now := time.Now()
+	if locked, err := accountLimiter.Fail(accountKey(username), now); err == nil && locked {
+		recordAudit(config.DB, c, "system", "account.locked", accountKey(username),
+			fmt.Sprintf("Locked for %d minutes after %d failed logins", int(config.LoginLockoutDuration.Minutes()), accountLimiter.Threshold))
+	}
+	if locked, err := ipLimiter.Fail(ipKey(c.ClientIP()), now); err == nil && locked {
+		recordAudit(config.DB, c, "system", "ip.locked", ipKey(c.ClientIP()),
+			fmt.Sprintf("Locked for %d minutes after %d failed logins", int(config.LoginLockoutDuration.Minutes()), ipLimiter.Threshold))
+	}
+}
+
+// loginSucceeded forgets the account's failures. The address keeps its
+// count, so one working account does not buy more guesses at others.
+func loginSucceeded(username string) {
+	accountLimiter.Reset(accountKey(username))
+}
+
+// UnlockUser - lets admin lift a login lockout on an account
+func UnlockUser(c *gin.Context) {
+	admin := c.MustGet("user").(models.User)
+	if !admin.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	var user models.User
+	if err := config.DB.First(&user, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := accountLimiter.Reset(accountKey(user.Username)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock user"})
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "account.unlocked", accountKey(user.Username), "")
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+	"shopping-cart/payments"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// setupTestDB points config.DB at a fresh database and payments.Provider at
+// a fresh mock gateway for the length of the test
+func setupTestDB(t *testing.T) *payments.MockGateway {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "shop.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.Item{},
+		&models.Cart{},
+		&models.CartItem{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.Promotion{},
+		&models.OrderAdjustment{},
+		&models.TaxRate{},
+		&models.OrderTax{},
+		&models.Address{},
+		&models.ShippingMethod{},
+		&models.Payment{},
+		&models.Refund{},
+		&models.WebhookEvent{},
+		&models.ReturnRequest{},
+		&models.ReturnItem{},
+		&models.OrderEvent{},
+		&models.Shipment{},
+		&models.ShipmentItem{},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	gateway := payments.NewMockGateway()
+	oldDB, oldProvider := config.DB, payments.Provider
+	config.DB, payments.Provider = db, gateway
+	t.Cleanup(func() {
+		config.DB, payments.Provider = oldDB, oldProvider
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return gateway
+}
+
+// checkoutFixture is a verified customer with a default address and a cart
+// holding two units of an item that has stock
+type checkoutFixture struct {
+	user   models.User
+	item   models.Item
+	cart   models.Cart
+	method models.ShippingMethod
+}
+
+func newCheckoutFixture(t *testing.T, stock int) checkoutFixture {
+	t.Helper()
+	now := time.Now()
+	email := "customer@example.com"
+	f := checkoutFixture{
+		user:   models.User{Username: "customer", Email: &email, EmailVerifiedAt: &now, Token: "token"},
+		item:   models.Item{Name: "Lamp", Price: 25, Stock: &stock},
+		method: models.ShippingMethod{Code: "standard", Name: "Standard", Type: models.ShippingFlat, BaseRate: 5},
+	}
+	mustCreate(t, &f.user, &f.item, &f.method)
+	mustCreate(t, &models.Address{UserID: f.user.ID, Name: "Customer", Line1: "1 Main St", City: "Springfield", Country: "US", IsDefault: true})
+	f.cart = models.Cart{UserID: f.user.ID}
+	mustCreate(t, &f.cart)
+	mustCreate(t, &models.CartItem{CartID: f.cart.ID, ItemID: f.item.ID, Price: f.item.Price, Quantity: 2})
+	return f
+}
+
+func mustCreate(t *testing.T, values ...any) {
+	t.Helper()
+	for _, value := range values {
+		if err := config.DB.Create(value).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// serve runs handler for one request made by user, who may be nil for
+// requests that need no login, and returns the response
+func serve(handler gin.HandlerFunc, user *models.User, method string, body any, header http.Header) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, "/", func(c *gin.Context) {
+		if user != nil {
+			c.Set("user", *user)
+		}
+		handler(c)
+	})
+
+	var payload []byte
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		payload = b
+	default:
+		payload, _ = json.Marshal(b)
+	}
+	req := httptest.NewRequest(method, "/", bytes.NewReader(payload))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
@@ -1,67 +1,220 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"shopping-cart/config"
 	"shopping-cart/models"
+	"shopping-cart/payments"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// setOrderStatus moves the order to status and records the change in its audit trail
+func setOrderStatus(tx *gorm.DB, order *models.Order, status, actor, note string) error {
+	from := order.Status
+	if err := tx.Model(order).Update("status", status).Error; err != nil {
+		return err
+	}
+	order.Status = status
+	return tx.Create(&models.OrderEvent{
+		OrderID:    order.ID,
+		Actor:      actor,
+		FromStatus: from,
+		ToStatus:   status,
+		Note:       note,
+	}).Error
+}
+
+// cancelOrder cancels the order and releases the stock and coupon it held.
+// Settling the payment is up to the caller.
+func cancelOrder(tx *gorm.DB, order *models.Order, actor, note string) error {
+	if err := setOrderStatus(tx, order, "cancelled", actor, note); err != nil {
+		return err
+	}
+	if err := releaseStock(tx, order); err != nil {
+		return err
+	}
+	return releaseCoupon(tx, order)
+}
+
 func CreateOrder(c *gin.Context) {
 	user := c.MustGet("user").(models.User)
-	var cart models.Cart
-	if err := config.DB.Where("user_id = ?", user.ID).First(&cart).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+	if user.EmailVerifiedAt == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Verify your email address before checking out"})
 		return
 	}
 
-	// Get cart items to calculate total
-	var cartItems []models.CartItem
-	if err := config.DB.Where("cart_id = ?", cart.ID).Find(&cartItems).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart items"})
+	var body struct {
+		ShippingAddressID uint   `json:"shipping_address_id"`
+		BillingAddressID  uint   `json:"billing_address_id"`
+		ShippingMethodID  uint   `json:"shipping_method_id"`
+		PaymentToken      string `json:"payment_token"`
+	}
+	if err := bindOptionalJSON(c, &body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	// Check if cart is empty
-	if len(cartItems) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot create order with empty cart"})
+	// Ship to the chosen address, or the user's default one
+	shipping, err := findShippingAddress(config.DB, user.ID, body.ShippingAddressID)
+	if err != nil {
+		if body.ShippingAddressID != 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Shipping address not found"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Shipping address is required"})
+		}
 		return
 	}
 
-	// Calculate total
-	var total float64
-	for _, item := range cartItems {
-		total += item.Price * float64(item.Quantity)
+	// Bill the shipping address unless a separate one is given
+	billing := shipping
+	if body.BillingAddressID != 0 {
+		if billing, err = findUserAddress(config.DB, user.ID, uint64(body.BillingAddressID)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Billing address not found"})
+			return
+		}
 	}
+	shipTo := shipping.Snapshot()
 
-	// Create order with total
-	order := models.Order{
-		CartID: cart.ID,
-		UserID: user.ID,
-		Total:  total,
-		Status: "completed",
+	if body.ShippingMethodID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Shipping method is required"})
+		return
 	}
-	if err := config.DB.Create(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+	var method models.ShippingMethod
+	if err := config.DB.First(&method, body.ShippingMethodID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Shipping method not found"})
 		return
 	}
 
-	// Clear cart items and delete cart
-	config.DB.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{})
-	config.DB.Delete(&cart)
+	var cart models.Cart
+	if err := config.DB.Where("user_id = ?", user.ID).First(&cart).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	var order models.Order
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Price the cart items inside the transaction so the total matches what is saved
+		priced, err := priceCart(tx, cart, user.ID, shipTo.TaxRegion())
+		if err != nil {
+			return err
+		}
+
+		// Check if cart is empty
+		if len(priced.Items) == 0 {
+			return &apiError{http.StatusBadRequest, "Cannot create order with empty cart"}
+		}
+		if priced.CouponError != "" {
+			return &apiError{http.StatusBadRequest, priced.CouponError}
+		}
+
+		quote := priced.Quote
+		if errMsg := quote.ApplyShipping(method, shipTo.Country); errMsg != "" {
+			return &apiError{http.StatusBadRequest, errMsg}
+		}
+
+		order = models.Order{
+			CartID:    cart.ID,
+			UserID:    user.ID,
+			Subtotal:  quote.Subtotal,
+			Discount:  quote.Discount,
+			Tax:       quote.Tax,
+			TaxMode:   quote.TaxMode,
+			TaxRegion: quote.TaxRegion,
+			Total:     quote.Total,
+			Status:    "pending",
+
+			ShippingMethod: quote.ShippingMethod,
+			ShippingCost:   quote.Shipping,
+
+			ShippingAddress: shipTo,
+			BillingAddress:  billing.Snapshot(),
+		}
+		if priced.Coupon != nil {
+			order.CouponCode = priced.Coupon.Code
+		}
+		for _, line := range quote.Lines {
+			order.Items = append(order.Items, models.OrderItem{
+				ItemID:   line.ItemID,
+				Name:     line.Name,
+				Price:    line.UnitPrice,
+				Quantity: line.Quantity,
+				Subtotal: line.Subtotal,
+				Discount: line.Discount,
+				Total:    line.Total,
+				TaxClass: line.TaxClass,
+				TaxRate:  line.TaxRate,
+				Tax:      line.Tax,
+			})
+		}
+		for _, adj := range quote.Adjustments {
+			order.Adjustments = append(order.Adjustments, models.OrderAdjustment{
+				Source:      adj.Source,
+				Code:        adj.Code,
+				Description: adj.Description,
+				Amount:      adj.Amount,
+			})
+		}
+		for _, tax := range quote.Taxes {
+			order.Taxes = append(order.Taxes, models.OrderTax{
+				Name:     tax.Name,
+				TaxClass: tax.TaxClass,
+				Rate:     tax.Rate,
+				Taxable:  tax.Taxable,
+				Amount:   tax.Amount,
+			})
+		}
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.OrderEvent{OrderID: order.ID, Actor: user.Username, ToStatus: order.Status, Note: "Order placed"}).Error; err != nil {
+			return err
+		}
+
+		if priced.Coupon != nil {
+			if err := redeemCoupon(tx, *priced.Coupon, user.ID, order.ID); err != nil {
+				return err
+			}
+		}
+
+		// Hold the stock until payment succeeds or fails
+		return reserveStock(tx, &order)
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create order")
+		return
+	}
 
-	// Reload order with cart items
-	var orderItems []models.CartItem
-	config.DB.Where("cart_id = ?", order.CartID).Find(&orderItems)
+	// The order stays pending until the payment is authorized
+	payment, err := authorizePayment(&order, body.PaymentToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+		return
+	}
+	order.Payments = []models.Payment{payment}
+	if payment.Status == payments.StatusFailed {
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": "Payment failed: " + payment.Message, "order": order})
+		return
+	}
 
+	message := "Order created successfully"
+	if payment.Status == payments.StatusPending {
+		message = "Order created, waiting for payment confirmation"
+	}
 	c.JSON(http.StatusCreated, gin.H{
-		"order":     order,
-		"items":     orderItems,
-		"total":     total,
-		"message":   "Order created successfully",
+		"order":       order,
+		"items":       order.Items,
+		"adjustments": order.Adjustments,
+		"taxes":       order.Taxes,
+		"payment":     payment,
+		"total":       order.Total,
+		"message":     message,
 	})
 }
 
@@ -69,7 +222,7 @@ func UserOrders(c *gin.Context) {
 	user := c.MustGet("user").(models.User)
 
 	var orders []models.Order
-	if err := config.DB.Preload("Cart").Where("user_id=?", user.ID).Find(&orders).Error; err != nil {
+	if err := config.DB.Preload("Cart").Preload("Items").Preload("Adjustments").Preload("Taxes").Preload("Payments").Preload("Returns.Items").Preload("Events").Preload("Shipments.Items").Where("user_id=?", user.ID).Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
 		return
 	}
@@ -85,7 +238,7 @@ func AdminOrders(c *gin.Context) {
 	}
 
 	var orders []models.Order
-	if err := config.DB.Preload("User").Preload("Cart").Find(&orders).Error; err != nil {
+	if err := config.DB.Preload("User", withoutSecrets).Preload("Cart").Preload("Items").Preload("Adjustments").Preload("Taxes").Preload("Payments").Preload("Returns.Items").Preload("Events").Preload("Shipments.Items").Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
 		return
 	}
@@ -93,6 +246,53 @@ func AdminOrders(c *gin.Context) {
 	c.JSON(http.StatusOK, orders)
 }
 
+// ownOrderByParam narrows query to the user's order whose number is the :id
+// parameter. Someone else's order and a missing one are both not found, so
+// order numbers cannot be probed.
+func ownOrderByParam(c *gin.Context, query *gorm.DB, userID uint) *gorm.DB {
+	return query.Where("number = ? AND user_id = ?", strings.ToUpper(c.Param("id")), userID)
+}
+
+// orderByParam narrows query to the order named by the :id parameter:
+// the customer's own order by number, or any order by number or ID for admins
+func orderByParam(c *gin.Context, query *gorm.DB, user models.User) *gorm.DB {
+	param := c.Param("id")
+	if !user.Admin {
+		return ownOrderByParam(c, query, user.ID)
+	}
+	if parsedID, err := strconv.ParseUint(param, 10, 64); err == nil {
+		return query.Where("id = ?", parsedID)
+	}
+	return query.Where("number = ?", strings.ToUpper(param))
+}
+
+// GetOrder - shows one order with its lines, payments, shipments, returns
+// and status history, looked up by order number
+func GetOrder(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	query := config.DB.Preload("Items").Preload("Adjustments").Preload("Taxes").Preload("Payments").
+		Preload("Returns.Items").Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("Shipments.Items")
+
+	var order models.Order
+	if err := orderByParam(c, query, user).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// orderTransitions are the statuses an admin can move an order to from each
+// status. Cancelled, refunded and failed orders are finished, and an order is
+// only completed once all of it has shipped.
+var orderTransitions = map[string][]string{
+	"pending":           {"processing", "cancelled"},
+	"processing":        {"shipped", "cancelled"},
+	"partially_shipped": {"shipped"},
+	"shipped":           {"completed"},
+}
+
 // UpdateOrderStatus - allows admin to update order status
 func UpdateOrderStatus(c *gin.Context) {
 	user := c.MustGet("user").(models.User)
@@ -101,13 +301,6 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	orderID := c.Param("id")
-	parsedID, err := strconv.ParseUint(orderID, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
-		return
-	}
-
 	var body struct {
 		Status string `json:"status"`
 	}
@@ -130,16 +323,161 @@ func UpdateOrderStatus(c *gin.Context) {
 	}
 
 	var order models.Order
-	if err := config.DB.First(&order, parsedID).Error; err != nil {
+	if err := orderByParam(c, config.DB.Preload("Items"), user).First(&order).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
 	}
 
-	order.Status = body.Status
-	if err := config.DB.Save(&order).Error; err != nil {
+	if order.Status == body.Status {
+		c.JSON(http.StatusOK, gin.H{"message": "Order status updated", "order": order})
+		return
+	}
+	if !slices.Contains(orderTransitions[order.Status], body.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot change a " + order.Status + " order to " + body.Status})
+		return
+	}
+
+	// Cancelling gives back the stock and the money
+	if body.Status == "cancelled" {
+		if err := cancelOrderPayment(&order, user.Username, "Cancelled by admin"); err != nil {
+			respondError(c, err, "Failed to cancel order")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Order status updated", "order": order})
+		return
+	}
+
+	// Shipping this way sends everything left in one parcel without tracking
+	if body.Status == "shipped" {
+		if _, err := createShipment(&order, "", "", nil, user.Username); err != nil {
+			respondError(c, err, "Failed to ship order")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Order status updated", "order": order})
+		return
+	}
+
+	if err := setOrderStatus(config.DB, &order, body.Status, user.Username, ""); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Order status updated", "order": order})
 }
+
+// CancelOrder - lets a customer cancel their own order before it ships
+func CancelOrder(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := bindOptionalJSON(c, &body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Admins cancel through PUT /orders/:id, so only the customer's own orders count
+	var order models.Order
+	if err := ownOrderByParam(c, config.DB, user.ID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	if order.Status != "pending" && order.Status != "processing" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only pending or processing orders can be cancelled"})
+		return
+	}
+
+	note := "Cancelled by customer"
+	if reason := strings.TrimSpace(body.Reason); reason != "" {
+		note += ": " + reason
+	}
+	if err := cancelOrderPayment(&order, user.Username, note); err != nil {
+		respondError(c, err, "Failed to cancel order")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order cancelled", "order": order})
+}
+
+// reorderLine reports what happened to one line of a reordered order
+type reorderLine struct {
+	ItemID   uint    `json:"item_id"`
+	Name     string  `json:"name"`
+	Quantity int     `json:"quantity"`
+	OldPrice float64 `json:"old_price,omitempty"`
+	NewPrice float64 `json:"new_price,omitempty"`
+	Reason   string  `json:"reason,omitempty"`
+}
+
+// Reorder - copies the lines of a past order into the user's cart. Lines
+// that can no longer be bought are skipped, and lines whose price changed
+// are added at today's price and reported.
+func Reorder(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var order models.Order
+	if err := ownOrderByParam(c, config.DB.Preload("Items"), user.ID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	added := []reorderLine{}
+	skipped := []reorderLine{}
+	priceChanged := []reorderLine{}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err := findOrCreateCart(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		for _, line := range order.Items {
+			report := reorderLine{ItemID: line.ItemID, Name: line.Name, Quantity: line.Quantity}
+
+			var item models.Item
+			if err := tx.First(&item, line.ItemID).Error; err != nil {
+				report.Reason = "Item is no longer available"
+				skipped = append(skipped, report)
+				continue
+			}
+
+			if _, err := addItemToCart(tx, cart, item, min(line.Quantity, 100)); err != nil {
+				var apiErr *apiError
+				var stockErr *stockError
+				switch {
+				case errors.As(err, &stockErr):
+					report.Reason = "Not enough stock"
+				case errors.As(err, &apiErr):
+					report.Reason = apiErr.Message
+				default:
+					return err
+				}
+				skipped = append(skipped, report)
+				continue
+			}
+
+			added = append(added, report)
+			if item.Price != line.Price {
+				report.OldPrice = line.Price
+				report.NewPrice = item.Price
+				priceChanged = append(priceChanged, report)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder"})
+		return
+	}
+
+	message := "Items added to cart"
+	if len(added) == 0 {
+		message = "None of the items can be ordered again"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       message,
+		"added":         added,
+		"skipped":       skipped,
+		"price_changed": priceChanged,
+	})
+}
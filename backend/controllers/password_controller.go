@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/lockout"
+	"shopping-cart/mail"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Reset links go out on the same cooldown as verification emails, per
+// email address whether or not it has an account, and a client address can
+// ask for a limited number a day
+var (
+	resetEmailLimiter = &lockout.Limiter{
+		Threshold:    config.VerificationResendLimit,
+		Window:       24 * time.Hour,
+		BaseDelay:    config.VerificationResendInterval,
+		MaxDelay:     config.VerificationResendInterval,
+		LockDuration: 24 * time.Hour,
+	}
+	resetIPLimiter = &lockout.Limiter{
+		Threshold:    config.LoginIPLockoutThreshold,
+		Window:       24 * time.Hour,
+		LockDuration: 24 * time.Hour,
+	}
+)
+
+// checkResetLimits refuses the request with 429 while the email address or
+// the client's address has to wait, and otherwise counts it
+func checkResetLimits(c *gin.Context, email string) bool {
+	now := time.Now()
+	emailKey, addressKey := "reset:"+email, "reset-ip:"+c.ClientIP()
+	emailWait, _, err := resetEmailLimiter.Check(emailKey, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check reset requests"})
+		return false
+	}
+	ipWait, _, err := resetIPLimiter.Check(addressKey, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check reset requests"})
+		return false
+	}
+	if wait := max(emailWait, ipWait); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another reset link"})
+		return false
+	}
+	resetEmailLimiter.Fail(emailKey, now)
+	resetIPLimiter.Fail(addressKey, now)
+	return true
+}
+
+// resetPasswordMessage is the email with a user's reset link
+func resetPasswordMessage(user models.User, token string) mail.Message {
+	link := config.AppURL + "/reset-password?token=" + url.QueryEscape(token)
+	return mail.Message{
+		To:      *user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your account. To choose a new one, open this link:\n\n"+
+			"%s\n\n"+
+			"The link works once and expires in %d minutes. If you did not ask for this, you can ignore this email.\n",
+			user.Username, link, int(config.PasswordResetTTL.Minutes())),
+	}
+}
+
+// ForgotPassword - mails a password reset link to the account with the given
+// email. The response is the same whether or not the account exists.
+func ForgotPassword(c *gin.Context) {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Email = strings.ToLower(strings.TrimSpace(body.Email))
+	if body.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		return
+	}
+
+	// Limited before the lookup, so the answer is the same for every address
+	if !checkResetLimits(c, body.Email) {
+		return
+	}
+
+	response := gin.H{"message": "If an account with that email exists, a reset link has been sent"}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", body.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, hash, err := newSecretToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+
+	// A new link replaces any earlier one that was not used
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(config.PasswordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+
+	// A failed send is only logged, so the response does not tell who has an account
+	if err := mail.Default.Send(resetPasswordMessage(user, token)); err != nil {
+		log.Printf("password reset mail for user %d: %v", user.ID, err)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword - sets a new password with a token from a reset link and
+// signs the user out everywhere
+func ResetPassword(c *gin.Context) {
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if body.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+		return
+	}
+	if body.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
+		return
+	}
+
+	// The link has to be valid before the password is checked against its user
+	var reset models.PasswordResetToken
+	var user models.User
+	if err := config.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashSecretToken(body.Token), time.Now()).First(&reset).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+		return
+	}
+	if err := config.DB.First(&user, reset.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+		return
+	}
+	if !validatePassword(c, body.Password, user.Username) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Mark the token used first so a second request with it cannot win a race
+		used := tx.Model(&models.PasswordResetToken{}).Where("id = ? AND used_at IS NULL", reset.ID).Update("used_at", time.Now())
+		if used.Error != nil {
+			return used.Error
+		}
+		if used.RowsAffected == 0 {
+			return &apiError{http.StatusBadRequest, "Reset link is invalid or has expired"}
+		}
+
+		// Clearing the token signs out every session
+		return tx.Model(&models.User{}).Where("id = ?", reset.UserID).Updates(map[string]any{
+			"password": string(hashedPassword),
+			"token":    "",
+		}).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to reset password")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in"})
+}
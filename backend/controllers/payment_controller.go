@@ -0,0 +1,503 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+	"shopping-cart/payments"
+	"shopping-cart/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reserveStock takes the ordered quantities out of stock for items that track it
+func reserveStock(tx *gorm.DB, order *models.Order) error {
+	for _, line := range order.Items {
+		var item models.Item
+		if err := tx.First(&item, line.ItemID).Error; err != nil || item.Archived {
+			return &apiError{http.StatusConflict, fmt.Sprintf("%s is no longer available", line.Name)}
+		}
+		if item.Stock == nil {
+			continue
+		}
+		result := tx.Model(&models.Item{}).
+			Where("id = ? AND stock >= ?", item.ID, line.Quantity).
+			UpdateColumn("stock", gorm.Expr("stock - ?", line.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &apiError{http.StatusConflict, fmt.Sprintf("Not enough stock for %s", line.Name)}
+		}
+	}
+	order.StockReserved = true
+	return tx.Model(order).Update("stock_reserved", true).Error
+}
+
+// releaseStock puts the stock reserved by the order back. It does nothing
+// if the order holds no stock, so it is safe to call more than once.
+func releaseStock(tx *gorm.DB, order *models.Order) error {
+	if !order.StockReserved {
+		return nil
+	}
+	var lines []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&lines).Error; err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := tx.Model(&models.Item{}).
+			Where("id = ? AND stock IS NOT NULL", line.ItemID).
+			UpdateColumn("stock", gorm.Expr("stock + ?", line.Quantity)).Error; err != nil {
+			return err
+		}
+	}
+	order.StockReserved = false
+	return tx.Model(order).Update("stock_reserved", false).Error
+}
+
+// releaseCoupon gives back the coupon use claimed by the order
+func releaseCoupon(tx *gorm.DB, order *models.Order) error {
+	var redemption models.CouponRedemption
+	if err := tx.Where("order_id = ?", order.ID).First(&redemption).Error; err != nil {
+		return nil
+	}
+	if err := tx.Delete(&redemption).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Coupon{}).
+		Where("id = ? AND used_count > 0", redemption.CouponID).
+		UpdateColumn("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// clearCart empties and removes the cart an order was placed from
+func clearCart(tx *gorm.DB, cartID uint) error {
+	if err := tx.Where("cart_id = ?", cartID).Delete(&models.CartItem{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&models.Cart{}, cartID).Error
+}
+
+// restoreCart puts the order's lines back into the user's cart, for when a
+// payment fails after the cart was already turned into the order
+func restoreCart(tx *gorm.DB, order *models.Order) error {
+	cart, err := findOrCreateCart(tx, order.UserID)
+	if err != nil {
+		return err
+	}
+	var lines []models.OrderItem
+	if err := tx.Where("order_id = ?", order.ID).Find(&lines).Error; err != nil {
+		return err
+	}
+	for _, line := range lines {
+		var cartItem models.CartItem
+		if err := tx.Where("cart_id = ? AND item_id = ?", cart.ID, line.ItemID).First(&cartItem).Error; err != nil {
+			cartItem = models.CartItem{CartID: cart.ID, ItemID: line.ItemID, Price: line.Price}
+		}
+		cartItem.Quantity = min(cartItem.Quantity+line.Quantity, 100)
+		if err := tx.Save(&cartItem).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failOrder marks an order whose payment failed and releases what it held
+func failOrder(tx *gorm.DB, order *models.Order, note string) error {
+	if err := setOrderStatus(tx, order, "failed", "gateway", note); err != nil {
+		return err
+	}
+	if err := releaseStock(tx, order); err != nil {
+		return err
+	}
+	return releaseCoupon(tx, order)
+}
+
+// authorizePayment asks the payment provider to authorize the order total
+// and records the outcome. An authorized order moves to processing and a
+// pending one waits for the gateway; either way the cart has become the
+// order and is cleared. A failed payment fails the order and keeps the cart
+// so the customer can try again. If the outcome cannot be recorded, the
+// authorization is voided and the order failed the same way, so a retried
+// checkout does not hold the stock and coupon twice.
+func authorizePayment(order *models.Order, token string) (models.Payment, error) {
+	result, err := payments.Provider.Authorize(payments.AuthorizeRequest{
+		OrderID: order.ID,
+		Amount:  order.Total,
+		Token:   token,
+	})
+	payment := models.Payment{
+		OrderID:   order.ID,
+		Provider:  payments.Provider.Name(),
+		Reference: result.Reference,
+		Amount:    order.Total,
+		Status:    result.Status,
+		Message:   result.Message,
+	}
+	if err != nil {
+		payment.Status = payments.StatusFailed
+		payment.Message = "Payment gateway unavailable"
+	}
+	if payment.Status == payments.StatusFailed && payment.Message == "" {
+		payment.Message = "Payment declined"
+	}
+
+	txErr := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		switch payment.Status {
+		case payments.StatusAuthorized:
+			if err := setOrderStatus(tx, order, "processing", "gateway", "Payment authorized"); err != nil {
+				return err
+			}
+			return clearCart(tx, order.CartID)
+		case payments.StatusPending:
+			return clearCart(tx, order.CartID)
+		default:
+			return failOrder(tx, order, payment.Message)
+		}
+	})
+	if txErr != nil {
+		if payment.Status == payments.StatusAuthorized || payment.Status == payments.StatusPending {
+			if _, err := payments.Provider.Void(payment.Reference); err != nil {
+				log.Printf("void payment %s for order %d: %v", payment.Reference, order.ID, err)
+			}
+		}
+		// The rolled back transaction may have moved the order on in memory
+		order.Status = "pending"
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return failOrder(tx, order, "Payment could not be recorded")
+		}); err != nil {
+			log.Printf("fail order %d: %v", order.ID, err)
+		}
+		return payment, txErr
+	}
+	return payment, nil
+}
+
+// captureOrderPayment captures the authorized payment of an order and saves
+// the result straight away, so what the gateway took is on record whatever
+// the caller does next. A payment captured earlier, e.g. with the first of
+// several shipments, is left as it is; without either there is no money to
+// take yet.
+func captureOrderPayment(order models.Order) error {
+	var payment models.Payment
+	taken := []string{payments.StatusAuthorized, payments.StatusCaptured, payments.StatusRefunded}
+	if err := config.DB.Where("order_id = ? AND status IN ?", order.ID, taken).Order("id desc").First(&payment).Error; err != nil {
+		return &apiError{http.StatusConflict, "Order has no authorized payment to capture"}
+	}
+	if payment.Status != payments.StatusAuthorized {
+		return nil
+	}
+	result, err := payments.Provider.Capture(payment.Reference, payment.Amount)
+	if err != nil || result.Status != payments.StatusCaptured {
+		return &apiError{http.StatusBadGateway, "Failed to capture payment"}
+	}
+	return config.DB.Model(&payment).Updates(map[string]any{
+		"status":          payments.StatusCaptured,
+		"captured_amount": payment.Amount,
+	}).Error
+}
+
+// issueRefund refunds amount through the payment provider against the
+// order's captured payment. A pending refund is written first and holds its
+// share of what is left to refund, so refunds made at the same time cannot
+// add up to more than was captured. The caller finishes it with
+// recordRefund; a refund the gateway turns down is marked failed.
+func issueRefund(order models.Order, amount float64) (models.Payment, models.Refund, error) {
+	var payment models.Payment
+	var refund models.Refund
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("order_id = ? AND status IN ?", order.ID, []string{payments.StatusCaptured, payments.StatusRefunded}).First(&payment).Error; err != nil {
+			return &apiError{http.StatusConflict, "Order has no captured payment to refund"}
+		}
+		var pending float64
+		if err := tx.Model(&models.Refund{}).Select("COALESCE(SUM(amount), 0)").
+			Where("payment_id = ? AND status = ?", payment.ID, models.RefundPending).
+			Scan(&pending).Error; err != nil {
+			return err
+		}
+		remaining := pricing.Round(payment.CapturedAmount - payment.RefundedAmount - pending)
+		if amount <= 0 || amount > remaining {
+			return &apiError{http.StatusBadRequest, fmt.Sprintf("Refund must be between 0 and %.2f", remaining)}
+		}
+		refund = models.Refund{PaymentID: payment.ID, OrderID: order.ID, Amount: amount, Status: models.RefundPending}
+		return tx.Create(&refund).Error
+	})
+	if err != nil {
+		return payment, refund, err
+	}
+
+	result, err := payments.Provider.Refund(payment.Reference, amount)
+	if err != nil || result.Status != payments.StatusRefunded {
+		refund.Status = models.RefundFailed
+		config.DB.Model(&refund).Updates(map[string]any{"status": refund.Status, "message": result.Message})
+		return payment, refund, &apiError{http.StatusBadGateway, "Failed to issue refund"}
+	}
+	return payment, refund, nil
+}
+
+// recordRefund marks a refund the gateway agreed to as succeeded and adds
+// it to the payment and the order. A refund the gateway's webhook already
+// settled is left alone, so it is not counted twice.
+func recordRefund(tx *gorm.DB, payment *models.Payment, order *models.Order, refund *models.Refund) error {
+	result := tx.Model(&models.Refund{}).Where("id = ? AND status = ?", refund.ID, models.RefundPending).Update("status", models.RefundSucceeded)
+	if result.Error != nil {
+		return result.Error
+	}
+	refund.Status = models.RefundSucceeded
+	if result.RowsAffected == 0 {
+		return tx.First(order, order.ID).Error
+	}
+	return addRefunded(tx, payment, order, refund.Amount)
+}
+
+// settleGatewayRefund brings the payment and the order up to the total the
+// gateway reports as refunded, capped at what was captured. Pending refunds
+// sent from here are settled first, oldest first; anything left was
+// refunded at the gateway directly and is recorded as a refund of its own.
+func settleGatewayRefund(tx *gorm.DB, payment *models.Payment, order *models.Order, total float64) error {
+	missing := pricing.Round(min(total, payment.CapturedAmount) - payment.RefundedAmount)
+	if missing <= 0 {
+		return nil
+	}
+	var pending []models.Refund
+	if err := tx.Where("payment_id = ? AND status = ?", payment.ID, models.RefundPending).Order("id").Find(&pending).Error; err != nil {
+		return err
+	}
+	for i := range pending {
+		if pending[i].Amount > missing {
+			break
+		}
+		if err := recordRefund(tx, payment, order, &pending[i]); err != nil {
+			return err
+		}
+		missing = pricing.Round(missing - pending[i].Amount)
+	}
+	if missing <= 0 {
+		return nil
+	}
+	refund := models.Refund{
+		PaymentID: payment.ID,
+		OrderID:   order.ID,
+		Amount:    missing,
+		Status:    models.RefundSucceeded,
+		Message:   "Refunded at the gateway",
+	}
+	if err := tx.Create(&refund).Error; err != nil {
+		return err
+	}
+	return addRefunded(tx, payment, order, missing)
+}
+
+// addRefunded adds a refunded amount to the payment and the order. Both are
+// read again first, as another refund may have been recorded since they
+// were loaded.
+func addRefunded(tx *gorm.DB, payment *models.Payment, order *models.Order, amount float64) error {
+	if err := tx.First(payment, payment.ID).Error; err != nil {
+		return err
+	}
+	payment.RefundedAmount = pricing.Round(payment.RefundedAmount + amount)
+	payment.Status = payments.StatusRefunded
+	if err := tx.Model(payment).Updates(map[string]any{"refunded_amount": payment.RefundedAmount, "status": payment.Status}).Error; err != nil {
+		return err
+	}
+	if err := tx.Select("refunded").First(order, order.ID).Error; err != nil {
+		return err
+	}
+	order.Refunded = pricing.Round(order.Refunded + amount)
+	return tx.Model(order).Update("refunded", order.Refunded).Error
+}
+
+// cancelOrderPayment cancels an order and settles its payment: an open
+// authorization is voided and anything already captured is refunded.
+// The gateway is called first so a failed void leaves the order untouched.
+// Should recording the outcome fail, the gateway's webhook catches up: a
+// void cancels the order and a refund settles the pending refund.
+func cancelOrderPayment(order *models.Order, actor, note string) error {
+	var payment models.Payment
+	open := []string{payments.StatusPending, payments.StatusAuthorized, payments.StatusCaptured, payments.StatusRefunded}
+	hasPayment := config.DB.Where("order_id = ? AND status IN ?", order.ID, open).Order("id desc").First(&payment).Error == nil
+
+	var refund models.Refund
+	if hasPayment {
+		switch payment.Status {
+		case payments.StatusPending, payments.StatusAuthorized:
+			result, err := payments.Provider.Void(payment.Reference)
+			if err != nil || result.Status != payments.StatusVoided {
+				return &apiError{http.StatusBadGateway, "Failed to void payment"}
+			}
+		default:
+			// Refunds still pending count as given back already
+			var pending float64
+			config.DB.Model(&models.Refund{}).Select("COALESCE(SUM(amount), 0)").
+				Where("payment_id = ? AND status = ?", payment.ID, models.RefundPending).Scan(&pending)
+			if amount := pricing.Round(payment.CapturedAmount - payment.RefundedAmount - pending); amount > 0 {
+				var err error
+				if payment, refund, err = issueRefund(*order, amount); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if hasPayment {
+			if payment.Status == payments.StatusPending || payment.Status == payments.StatusAuthorized {
+				if err := tx.Model(&payment).Update("status", payments.StatusVoided).Error; err != nil {
+					return err
+				}
+			} else if refund.ID != 0 {
+				if err := recordRefund(tx, &payment, order, &refund); err != nil {
+					return err
+				}
+			}
+		}
+		return cancelOrder(tx, order, actor, note)
+	})
+}
+
+// errEventTooEarly rejects an event that depends on one not received yet.
+// It is not recorded, so the gateway's retry can apply it later.
+var errEventTooEarly = &apiError{http.StatusConflict, "Event arrived before the events it follows, retry later"}
+
+// applyPaymentEvent moves the payment to the status reported by a webhook
+// event, and the order with it. Events that would move a payment backwards,
+// such as an authorization arriving after the capture, are ignored. Events
+// that skip ahead, such as a refund arriving before the capture, fail with
+// errEventTooEarly.
+func applyPaymentEvent(tx *gorm.DB, payment *models.Payment, status string, event payments.Event) (bool, error) {
+	// Refund events carry the total refunded so far, so only larger totals count
+	if status == payments.StatusRefunded && payment.Status == payments.StatusRefunded && event.Amount <= payment.RefundedAmount {
+		return false, nil
+	}
+	if !payments.CanTransition(payment.Status, status) {
+		if payments.CanReach(payment.Status, status) {
+			return false, errEventTooEarly
+		}
+		return false, nil
+	}
+
+	var order models.Order
+	if err := tx.First(&order, payment.OrderID).Error; err != nil {
+		return false, err
+	}
+
+	updates := map[string]any{"status": status}
+	if event.Message != "" {
+		updates["message"] = event.Message
+	}
+	switch status {
+	case payments.StatusAuthorized, payments.StatusCaptured:
+		if status == payments.StatusCaptured {
+			updates["captured_amount"] = payment.Amount
+			if event.Amount > 0 {
+				updates["captured_amount"] = event.Amount
+			}
+		}
+		if order.Status == "pending" {
+			if err := setOrderStatus(tx, &order, "processing", "gateway", "Payment "+status); err != nil {
+				return false, err
+			}
+		}
+	case payments.StatusFailed:
+		if order.Status == "pending" {
+			if err := failOrder(tx, &order, event.Message); err != nil {
+				return false, err
+			}
+			if err := restoreCart(tx, &order); err != nil {
+				return false, err
+			}
+		}
+	case payments.StatusVoided:
+		if order.Status == "pending" || order.Status == "processing" {
+			if err := cancelOrder(tx, &order, "gateway", "Payment voided"); err != nil {
+				return false, err
+			}
+		}
+	case payments.StatusRefunded:
+		// Refunded the same way as from here, so the order shows it too
+		if err := settleGatewayRefund(tx, payment, &order, event.Amount); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Model(payment).Updates(updates).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PaymentWebhook - receives signed payment events from the gateway. Each
+// event is applied once; redeliveries are acknowledged without effect.
+// Events that arrive too early are answered with 409 and not recorded, so
+// the gateway delivers them again.
+func PaymentWebhook(c *gin.Context) {
+	if config.PaymentWebhookSecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Webhooks are not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if !payments.VerifySignature(config.PaymentWebhookSecret, body, c.GetHeader(payments.SignatureHeader)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	var event payments.Event
+	if err := json.Unmarshal(body, &event); err != nil || event.ID == "" || event.Reference == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event"})
+		return
+	}
+
+	result := "ignored"
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.WebhookEvent
+		if err := tx.Where("event_id = ?", event.ID).First(&existing).Error; err == nil {
+			result = "duplicate"
+			return nil
+		}
+
+		// Unknown references are rejected without recording the event,
+		// so the gateway's retry can apply it once the payment exists
+		var payment models.Payment
+		if err := tx.Where("reference = ?", event.Reference).First(&payment).Error; err != nil {
+			return &apiError{http.StatusNotFound, "Unknown payment reference"}
+		}
+
+		if status, ok := payments.EventStatus(event.Type); ok {
+			applied, err := applyPaymentEvent(tx, &payment, status, event)
+			if err != nil {
+				return err
+			}
+			if applied {
+				result = "applied"
+			}
+		}
+
+		return tx.Create(&models.WebhookEvent{
+			EventID:    event.ID,
+			Type:       event.Type,
+			Reference:  event.Reference,
+			Payload:    string(body),
+			Result:     result,
+			ReceivedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to process event")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"received": true, "result": result})
+}
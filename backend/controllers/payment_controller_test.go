@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+	"shopping-cart/payments"
+)
+
+func TestCreateOrderPayment(t *testing.T) {
+	tests := []struct {
+		name        string
+		token       string
+		script      []payments.Outcome
+		breakRecord bool // drop the payments table so the outcome cannot be saved
+		code        int
+		status      string // order status
+		payment     string // payment status, empty when none is saved
+		stock       int    // left of the 10 in stock
+		cartItems   int64
+	}{
+		{name: "authorized", token: "tok_ok", code: http.StatusCreated, status: "processing", payment: payments.StatusAuthorized, stock: 8, cartItems: 0},
+		{name: "declined", token: payments.MockTokenDecline, code: http.StatusPaymentRequired, status: "failed", payment: payments.StatusFailed, stock: 10, cartItems: 1},
+		{name: "pending", token: payments.MockTokenPending, code: http.StatusCreated, status: "pending", payment: payments.StatusPending, stock: 8, cartItems: 0},
+		{
+			name:   "scripted decline",
+			token:  "tok_ok",
+			script: []payments.Outcome{{Status: payments.StatusFailed, Message: "Insufficient funds"}},
+			code:   http.StatusPaymentRequired, status: "failed", payment: payments.StatusFailed, stock: 10, cartItems: 1,
+		},
+		{
+			name:   "gateway unavailable",
+			token:  "tok_ok",
+			script: []payments.Outcome{{Err: errors.New("connection refused")}},
+			code:   http.StatusPaymentRequired, status: "failed", payment: payments.StatusFailed, stock: 10, cartItems: 1,
+		},
+		{name: "outcome not saved", token: "tok_ok", breakRecord: true, code: http.StatusInternalServerError, status: "failed", stock: 10, cartItems: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gateway := setupTestDB(t)
+			gateway.Script(tt.script...)
+			f := newCheckoutFixture(t, 10)
+			if tt.breakRecord {
+				if err := config.DB.Migrator().DropTable(&models.Payment{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rec := serve(CreateOrder, &f.user, http.MethodPost, map[string]any{
+				"shipping_method_id": f.method.ID,
+				"payment_token":      tt.token,
+			}, nil)
+			if rec.Code != tt.code {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.code, rec.Body)
+			}
+
+			var order models.Order
+			if err := config.DB.First(&order).Error; err != nil {
+				t.Fatal(err)
+			}
+			if order.Status != tt.status {
+				t.Errorf("order is %s, want %s", order.Status, tt.status)
+			}
+			if tt.payment != "" {
+				var payment models.Payment
+				if err := config.DB.Where("order_id = ?", order.ID).First(&payment).Error; err != nil {
+					t.Fatal(err)
+				}
+				if payment.Status != tt.payment {
+					t.Errorf("payment is %s, want %s", payment.Status, tt.payment)
+				}
+			}
+
+			var item models.Item
+			config.DB.First(&item, f.item.ID)
+			if *item.Stock != tt.stock {
+				t.Errorf("stock is %d, want %d", *item.Stock, tt.stock)
+			}
+			if order.StockReserved != (tt.stock < 10) {
+				t.Errorf("order holds stock: %v", order.StockReserved)
+			}
+
+			var cartItems int64
+			config.DB.Model(&models.CartItem{}).Where("cart_id = ?", f.cart.ID).Count(&cartItems)
+			if cartItems != tt.cartItems {
+				t.Errorf("cart has %d lines, want %d", cartItems, tt.cartItems)
+			}
+		})
+	}
+}
+
+// webhookFixtures reads the events in a testdata directory, in file name
+// order, for the payment with reference
+func webhookFixtures(t *testing.T, dir, reference string) [][]byte {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("..", "payments", "testdata", "webhooks", dir, "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no fixtures in %s: %v", dir, err)
+	}
+	sort.Strings(files)
+	var events [][]byte
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, bytes.ReplaceAll(body, []byte("{{reference}}"), []byte(reference)))
+	}
+	return events
+}
+
+// deliverWebhooks posts the events like the gateway does, sending an event
+// again later for as long as it is answered with an error
+func deliverWebhooks(t *testing.T, events [][]byte) {
+	t.Helper()
+	queue := append([][]byte(nil), events...)
+	for attempts := 0; len(queue) > 0; attempts++ {
+		if attempts > len(events)*len(events) {
+			t.Fatalf("%d events were never accepted", len(queue))
+		}
+		body := queue[0]
+		queue = queue[1:]
+		header := http.Header{payments.SignatureHeader: {payments.Sign(config.PaymentWebhookSecret, body)}}
+		rec := serve(PaymentWebhook, nil, http.MethodPost, body, header)
+		switch rec.Code {
+		case http.StatusOK:
+		case http.StatusConflict:
+			queue = append(queue, body)
+		default:
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestPaymentWebhookOutOfOrder(t *testing.T) {
+	oldSecret := config.PaymentWebhookSecret
+	config.PaymentWebhookSecret = "whsec_test"
+	t.Cleanup(func() { config.PaymentWebhookSecret = oldSecret })
+
+	for seed := int64(0); seed < 25; seed++ {
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			setupTestDB(t)
+			order := models.Order{UserID: 1, Total: 300, Status: "processing"}
+			mustCreate(t, &order)
+			payment := models.Payment{OrderID: order.ID, Provider: "mock", Reference: "mock_test", Amount: 300, Status: payments.StatusAuthorized}
+			mustCreate(t, &payment)
+
+			// Seed 0 keeps file order; each event also arrives twice
+			events := webhookFixtures(t, "out-of-order", payment.Reference)
+			events = append(events, events...)
+			if seed > 0 {
+				rand.New(rand.NewSource(seed)).Shuffle(len(events), func(i, j int) {
+					events[i], events[j] = events[j], events[i]
+				})
+			}
+			deliverWebhooks(t, events)
+
+			config.DB.First(&payment, payment.ID)
+			config.DB.First(&order, order.ID)
+			if payment.Status != payments.StatusRefunded || payment.CapturedAmount != 300 || payment.RefundedAmount != 150 {
+				t.Errorf("payment is %s, captured %.2f, refunded %.2f; want refunded, 300, 150", payment.Status, payment.CapturedAmount, payment.RefundedAmount)
+			}
+			if order.Refunded != 150 {
+				t.Errorf("order refunded %.2f, want 150", order.Refunded)
+			}
+			var recorded int64
+			config.DB.Model(&models.WebhookEvent{}).Count(&recorded)
+			if recorded != 4 {
+				t.Errorf("%d events recorded, want 4", recorded)
+			}
+		})
+	}
+}
+
+func TestPaymentWebhookDeclined(t *testing.T) {
+	oldSecret := config.PaymentWebhookSecret
+	config.PaymentWebhookSecret = "whsec_test"
+	t.Cleanup(func() { config.PaymentWebhookSecret = oldSecret })
+
+	setupTestDB(t)
+	f := newCheckoutFixture(t, 10)
+	rec := serve(CreateOrder, &f.user, http.MethodPost, map[string]any{
+		"shipping_method_id": f.method.ID,
+		"payment_token":      payments.MockTokenPending,
+	}, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var payment models.Payment
+	config.DB.First(&payment)
+
+	deliverWebhooks(t, webhookFixtures(t, "declined", payment.Reference))
+
+	var order models.Order
+	config.DB.First(&order, payment.OrderID)
+	config.DB.First(&payment, payment.ID)
+	if payment.Status != payments.StatusFailed || order.Status != "failed" {
+		t.Errorf("payment is %s and order %s, want both failed", payment.Status, order.Status)
+	}
+	var item models.Item
+	config.DB.First(&item, f.item.ID)
+	if *item.Stock != 10 {
+		t.Errorf("stock is %d, want 10", *item.Stock)
+	}
+	var cartItems int64
+	config.DB.Model(&models.CartItem{}).Where("item_id = ?", f.item.ID).Count(&cartItems)
+	if cartItems != 1 {
+		t.Errorf("cart has %d lines, want the order's line back", cartItems)
+	}
+}
@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/mail"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// accountExport is everything the shop keeps about a user
+type accountExport struct {
+	ExportedAt time.Time             `json:"exported_at"`
+	Profile    profileResponse       `json:"profile"`
+	Addresses  []models.Address      `json:"addresses"`
+	Carts      []models.Cart         `json:"carts"`
+	Wishlist   []models.WishlistItem `json:"wishlist"`
+	Orders     []models.Order        `json:"orders"`
+	Reviews    []models.Review       `json:"reviews"`
+}
+
+// erasureResponse is an erasure request as its customer sees it
+type erasureResponse struct {
+	ID           uint       `json:"id"`
+	Status       string     `json:"status"`
+	Reason       string     `json:"reason"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	CancelledAt  *time.Time `json:"cancelled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func newErasureResponse(request models.ErasureRequest) erasureResponse {
+	return erasureResponse{
+		ID:           request.ID,
+		Status:       request.Status,
+		Reason:       request.Reason,
+		ScheduledFor: request.ScheduledFor,
+		CancelledAt:  request.CancelledAt,
+		CreatedAt:    request.CreatedAt,
+	}
+}
+
+// findPendingErasure returns the user's erasure request that has not been
+// cancelled or carried out yet
+func findPendingErasure(db *gorm.DB, userID uint) (models.ErasureRequest, bool) {
+	var request models.ErasureRequest
+	err := db.Where("user_id = ? AND status = ?", userID, models.ErasurePending).First(&request).Error
+	return request, err == nil
+}
+
+// sendErasureEmail tells the user when their account will be erased and
+// how to stop it
+func sendErasureEmail(user models.User, request models.ErasureRequest) error {
+	if user.Email == nil {
+		return nil
+	}
+	return mail.Default.Send(mail.Message{
+		To:      *user.Email,
+		Subject: "Your account will be erased",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"We received a request to erase your account. It will be erased after %s.\n\n"+
+			"If you change your mind, log in before then and cancel the request. "+
+			"If you did not ask for this, change your password now.\n",
+			user.Username, request.ScheduledFor.UTC().Format("2 January 2006 15:04 MST")),
+	})
+}
+
+// ExportAccount - lets the user download everything the shop keeps about
+// them as a JSON file
+func ExportAccount(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	// Personal data goes to its owner only
+	if _, impersonating := c.Get("impersonation"); impersonating {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a customer"})
+		return
+	}
+
+	export := accountExport{ExportedAt: time.Now(), Profile: newProfileResponse(user)}
+	queries := []struct {
+		query *gorm.DB
+		dest  any
+	}{
+		{config.DB.Where("user_id = ?", user.ID).Order("id"), &export.Addresses},
+		{config.DB.Preload("Coupon").Preload("CartItems.Item").Where("user_id = ?", user.ID), &export.Carts},
+		{config.DB.Preload("Item").Joins("JOIN wishlists ON wishlists.id = wishlist_items.wishlist_id").Where("wishlists.user_id = ?", user.ID).Order("wishlist_items.id"), &export.Wishlist},
+		{config.DB.Preload("Items").Preload("Adjustments").Preload("Taxes").Preload("Payments").Preload("Returns.Items").Preload("Events").Preload("Shipments.Items").Where("user_id = ?", user.ID).Order("id"), &export.Orders},
+		{config.DB.Where("user_id = ?", user.ID).Order("id"), &export.Reviews},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+			return
+		}
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d.json"`, user.ID))
+	c.JSON(http.StatusOK, export)
+}
+
+// GetErasureRequest - shows the user's pending erasure request
+func GetErasureRequest(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	request, ok := findPendingErasure(config.DB, user.ID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No erasure request"})
+		return
+	}
+	c.JSON(http.StatusOK, newErasureResponse(request))
+}
+
+// RequestErasure - asks for the user's account to be erased. Nothing is
+// erased until the cooling-off period is over, and the request can be
+// cancelled until then.
+func RequestErasure(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var body struct {
+		Password string `json:"password"`
+		Reason   string `json:"reason"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Wrong passwords count as failed logins, as with password changes
+	if !checkLoginLimits(c, user.Username) {
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
+		loginFailed(c, user.Username)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+	if _, ok := findPendingErasure(config.DB, user.ID); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "Erasure already requested"})
+		return
+	}
+
+	request := models.ErasureRequest{
+		UserID:       user.ID,
+		Status:       models.ErasurePending,
+		Reason:       strings.TrimSpace(body.Reason),
+		ScheduledFor: time.Now().Add(config.ErasureCoolingOff),
+	}
+	if err := config.DB.Create(&request).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request erasure"})
+		return
+	}
+	recordAudit(config.DB, c, user.Username, "erasure.requested", accountKey(user.Username), "Scheduled for "+request.ScheduledFor.UTC().Format(time.RFC3339))
+
+	if err := sendErasureEmail(user, request); err != nil {
+		log.Printf("erasure mail for user %d: %v", user.ID, err)
+	}
+	c.JSON(http.StatusCreated, newErasureResponse(request))
+}
+
+// CancelErasure - withdraws the user's pending erasure request
+func CancelErasure(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	request, ok := findPendingErasure(config.DB, user.ID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No erasure request"})
+		return
+	}
+
+	now := time.Now()
+	request.Status = models.ErasureCancelled
+	request.CancelledAt = &now
+	if err := config.DB.Model(&request).Updates(map[string]any{"status": request.Status, "cancelled_at": now}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel erasure"})
+		return
+	}
+	recordAudit(config.DB, c, user.Username, "erasure.cancelled", accountKey(user.Username), "")
+	c.JSON(http.StatusOK, newErasureResponse(request))
+}
+
+// ListErasureRequests - lets admin see erasure requests, pending ones by
+// default or with ?status= another status. Requests that are due come first.
+func ListErasureRequests(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.ErasurePending)
+	var requests []models.ErasureRequest
+	if err := config.DB.Preload("User", withoutSecrets).Where("status = ?", status).Order("scheduled_for").Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch erasure requests"})
+		return
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+// CompleteErasure - lets admin carry out an erasure request once its
+// cooling-off period is over. The account is anonymized like a deleted
+// one; its orders are kept for the accounts.
+func CompleteErasure(c *gin.Context) {
+	admin := c.MustGet("user").(models.User)
+	if !admin.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid erasure request ID"})
+		return
+	}
+
+	var request models.ErasureRequest
+	if err := config.DB.First(&request, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Erasure request not found"})
+		return
+	}
+	if request.Status != models.ErasurePending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Erasure request is " + request.Status})
+		return
+	}
+	if time.Now().Before(request.ScheduledFor) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cooling-off period ends at " + request.ScheduledFor.UTC().Format(time.RFC3339)})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, request.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	var open int64
+	config.DB.Model(&models.Order{}).Where("user_id = ? AND status IN ?", user.ID, openOrderStatuses).Count(&open)
+	if open > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User has orders in progress"})
+		return
+	}
+
+	subject := accountKey(user.Username)
+	now := time.Now()
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// The customer may have cancelled in the meantime
+		result := tx.Model(&request).Where("status = ?", models.ErasurePending).Updates(map[string]any{
+			"status":       models.ErasureCompleted,
+			"completed_at": now,
+			"completed_by": admin.Username,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &apiError{http.StatusConflict, "Erasure request is no longer pending"}
+		}
+		// An account an admin already deleted has nothing left to erase
+		if user.AnonymizedAt != nil {
+			return nil
+		}
+		return anonymizeUser(tx, &user)
+	})
+	if err != nil {
+		respondError(c, err, "Failed to erase account")
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "erasure.completed", subject, fmt.Sprintf("Request %d, anonymized as %s", request.ID, user.Username))
+	c.JSON(http.StatusOK, gin.H{"message": "Account erased"})
+}
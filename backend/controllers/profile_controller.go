@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// preferencesResponse is how a user's preferences are shown
+type preferencesResponse struct {
+	Newsletter  bool `json:"newsletter"`
+	OrderEmails bool `json:"order_emails"`
+}
+
+// profileResponse is a user's account as shown to themselves. It leaves
+// out the password hash and session token.
+type profileResponse struct {
+	ID               uint                `json:"id"`
+	Username         string              `json:"username"`
+	DisplayName      string              `json:"display_name"`
+	Email            *string             `json:"email"`
+	EmailVerified    bool                `json:"email_verified"`
+	Admin            bool                `json:"admin"`
+	TwoFactorEnabled bool                `json:"two_factor_enabled"`
+	Preferences      preferencesResponse `json:"preferences"`
+}
+
+func newProfileResponse(user models.User) profileResponse {
+	_, twoFactor := findTwoFactor(config.DB, user.ID)
+	return profileResponse{
+		ID:               user.ID,
+		Username:         user.Username,
+		DisplayName:      user.DisplayName,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		Admin:            user.Admin,
+		TwoFactorEnabled: twoFactor,
+		Preferences: preferencesResponse{
+			Newsletter:  user.Preferences.Newsletter,
+			OrderEmails: user.Preferences.OrderEmails,
+		},
+	}
+}
+
+// GetProfile - returns the logged in user's account
+func GetProfile(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	c.JSON(http.StatusOK, newProfileResponse(user))
+}
+
+// UpdateProfile - lets users change their display name, email and
+// preferences. Fields left out are not changed. A new email has to be
+// verified again before the next checkout.
+func UpdateProfile(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var body struct {
+		DisplayName *string `json:"display_name"`
+		Email       *string `json:"email"`
+		Preferences *struct {
+			Newsletter  *bool `json:"newsletter"`
+			OrderEmails *bool `json:"order_emails"`
+		} `json:"preferences"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	updates := map[string]any{}
+	if body.DisplayName != nil {
+		name := strings.TrimSpace(*body.DisplayName)
+		if len(name) > 50 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Display name must be less than 50 characters"})
+			return
+		}
+		updates["display_name"] = name
+	}
+
+	emailChanged := false
+	if body.Email != nil {
+		email := normalizeEmail(*body.Email)
+		if errMsg := validateEmail(email); errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+			return
+		}
+		if user.Email == nil || *user.Email != email {
+			if emailTaken(config.DB, email, user.ID) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+				return
+			}
+			updates["email"] = email
+			updates["email_verified_at"] = nil
+			emailChanged = true
+		}
+	}
+
+	if body.Preferences != nil {
+		if body.Preferences.Newsletter != nil {
+			updates["pref_newsletter"] = *body.Preferences.Newsletter
+		}
+		if body.Preferences.OrderEmails != nil {
+			updates["pref_order_emails"] = *body.Preferences.OrderEmails
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
+	}
+	if err := config.DB.First(&user, user.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load profile"})
+		return
+	}
+
+	if emailChanged {
+		if err := sendVerificationEmail(config.DB, user); err != nil {
+			log.Printf("verification mail for user %d: %v", user.ID, err)
+		}
+	}
+	c.JSON(http.StatusOK, newProfileResponse(user))
+}
+
+// ChangePassword - sets a new password given the current one. Other
+// sessions are signed out; the caller gets a new token to stay logged in.
+func ChangePassword(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var body struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	// Wrong current passwords count as failed logins, so a stolen session
+	// cannot be used to guess the password
+	if !checkLoginLimits(c, user.Username) {
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.CurrentPassword)); err != nil {
+		loginFailed(c, user.Username)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if body.NewPassword == body.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be different from the current one"})
+		return
+	}
+	if !validatePassword(c, body.NewPassword, user.Username) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(body.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		return
+	}
+
+	token := uuid.NewString()
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Reset links sent for the old password stop working too
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]any{"password": string(hashedPassword), "token": token}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	recordAudit(config.DB, c, user.Username, "password.changed", accountKey(user.Username), "")
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed", "token": token})
+}
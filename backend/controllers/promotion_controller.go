@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validatePromotion checks that a promotion rule is well formed
+func validatePromotion(promo models.Promotion) string {
+	if promo.Name == "" {
+		return "Promotion name is required"
+	}
+	if len(promo.Name) > 100 {
+		return "Promotion name must be less than 100 characters"
+	}
+	if promo.StartsAt != nil && promo.EndsAt != nil && promo.EndsAt.Before(*promo.StartsAt) {
+		return "Promotion must end after it starts"
+	}
+
+	switch promo.Type {
+	case models.PromotionBuyXGetY:
+		if promo.BuyQuantity <= 0 || promo.GetQuantity <= 0 {
+			return "Buy and get quantities must be at least 1"
+		}
+		if len(promo.ItemIDs) == 0 {
+			return "Buy X get Y promotions need at least one item"
+		}
+	case models.PromotionBundle:
+		if len(promo.ItemIDs) < 2 {
+			return "Bundles need at least two items"
+		}
+		seen := map[uint]bool{}
+		for _, id := range promo.ItemIDs {
+			if seen[id] {
+				return "Bundle items must be distinct"
+			}
+			seen[id] = true
+		}
+		if promo.BundlePrice <= 0 {
+			return "Bundle price must be positive"
+		}
+	case models.PromotionTiered:
+		if len(promo.Tiers) == 0 {
+			return "Tiered promotions need at least one tier"
+		}
+		for _, tier := range promo.Tiers {
+			if tier.MinQuantity < 1 {
+				return "Tier minimum quantity must be at least 1"
+			}
+			if tier.PercentOff <= 0 || tier.PercentOff > 100 {
+				return "Tier discount must be between 0 and 100 percent"
+			}
+		}
+	default:
+		return "Promotion type must be buy_x_get_y, bundle or tiered"
+	}
+	return ""
+}
+
+func CreatePromotion(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var promo models.Promotion
+	if err := c.BindJSON(&promo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	promo.ID = 0
+
+	if errMsg := validatePromotion(promo); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	if err := config.DB.Create(&promo).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promotion"})
+		return
+	}
+	c.JSON(http.StatusCreated, promo)
+}
+
+func ListPromotions(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var promotions []models.Promotion
+	config.DB.Order("priority desc, id").Find(&promotions)
+	c.JSON(http.StatusOK, promotions)
+}
+
+func DeletePromotion(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion ID"})
+		return
+	}
+
+	if err := config.DB.Delete(&models.Promotion{}, parsedID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete promotion"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted"})
+}
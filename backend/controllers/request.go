@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// bindOptionalJSON binds the JSON body if there is one, so endpoints that
+// used to take no body keep working for older clients
+func bindOptionalJSON(c *gin.Context, obj any) error {
+	if c.Request.ContentLength == 0 {
+		return nil
+	}
+	return c.ShouldBindJSON(obj)
+}
@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+	"shopping-cart/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// unitRefund is what the customer paid for one unit of an order line,
+// after discounts and including tax that was charged on top
+func unitRefund(order models.Order, line models.OrderItem) float64 {
+	if line.Quantity == 0 {
+		return 0
+	}
+	paid := line.Total
+	if order.TaxMode != pricing.TaxInclusive {
+		paid += line.Tax
+	}
+	return paid / float64(line.Quantity)
+}
+
+// openReturnQuantities counts the units of each order line that are already
+// part of a return that was not rejected
+func openReturnQuantities(db *gorm.DB, orderID uint) (map[uint]int, error) {
+	var rows []struct {
+		OrderItemID uint
+		Quantity    int
+	}
+	err := db.Model(&models.ReturnItem{}).
+		Select("return_items.order_item_id, SUM(return_items.quantity) AS quantity").
+		Joins("JOIN return_requests ON return_requests.id = return_items.return_request_id").
+		Where("return_requests.order_id = ? AND return_requests.status <> ?", orderID, models.ReturnRejected).
+		Group("return_items.order_item_id").
+		Scan(&rows).Error
+	quantities := map[uint]int{}
+	for _, row := range rows {
+		quantities[row.OrderItemID] = row.Quantity
+	}
+	return quantities, err
+}
+
+// findReturn loads a return request with its items for the admin actions
+func findReturn(c *gin.Context) (models.ReturnRequest, bool) {
+	var rma models.ReturnRequest
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid return ID"})
+		return rma, false
+	}
+	if err := config.DB.Preload("Items").First(&rma, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Return not found"})
+		return rma, false
+	}
+	return rma, true
+}
+
+// claimReturn moves the return from one status to the next only if it is
+// still in the first, so two admins acting at once cannot both do the work
+func claimReturn(tx *gorm.DB, rma *models.ReturnRequest, from, to, conflict string) error {
+	result := tx.Model(&models.ReturnRequest{}).Where("id = ? AND status = ?", rma.ID, from).Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return &apiError{http.StatusConflict, conflict}
+	}
+	rma.Status = to
+	return nil
+}
+
+// CreateReturn - opens a return request for lines of a shipped or completed
+// order, or for the lines of a partially shipped order that have shipped
+func CreateReturn(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var body struct {
+		Reason string `json:"reason"`
+		Items  []struct {
+			OrderItemID uint `json:"order_item_id"`
+			Quantity    int  `json:"quantity"`
+		} `json:"items"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return
+	}
+	if len(body.Items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Choose at least one item to return"})
+		return
+	}
+
+	var order models.Order
+	if err := ownOrderByParam(c, config.DB.Preload("Items"), user.ID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if order.Status != "shipped" && order.Status != "completed" && order.Status != "partially_shipped" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only shipped or completed orders can be returned"})
+		return
+	}
+
+	rma := models.ReturnRequest{OrderID: order.ID, OrderNumber: *order.Number, UserID: user.ID, Status: models.ReturnRequested, Reason: body.Reason}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		open, err := openReturnQuantities(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		for _, requested := range body.Items {
+			var line *models.OrderItem
+			for i := range order.Items {
+				if order.Items[i].ID == requested.OrderItemID {
+					line = &order.Items[i]
+				}
+			}
+			if line == nil {
+				return &apiError{http.StatusBadRequest, "Item is not part of this order"}
+			}
+			// Only what has left the warehouse can come back
+			returnable := line.Quantity
+			if order.Status == "partially_shipped" {
+				returnable = line.ShippedQuantity
+			}
+			available := returnable - open[line.ID]
+			if requested.Quantity <= 0 || requested.Quantity > available {
+				return &apiError{http.StatusBadRequest, fmt.Sprintf("You can return up to %d of %s", available, line.Name)}
+			}
+			open[line.ID] += requested.Quantity
+
+			refund := pricing.Round(unitRefund(order, *line) * float64(requested.Quantity))
+			rma.Items = append(rma.Items, models.ReturnItem{OrderItemID: line.ID, Quantity: requested.Quantity, RefundAmount: refund})
+			rma.RefundAmount = pricing.Round(rma.RefundAmount + refund)
+		}
+		return tx.Create(&rma).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create return")
+		return
+	}
+	c.JSON(http.StatusCreated, rma)
+}
+
+// UserReturns - lists the user's return requests
+func UserReturns(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var returns []models.ReturnRequest
+	if err := config.DB.Preload("Items").Where("user_id = ?", user.ID).Order("id desc").Find(&returns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch returns"})
+		return
+	}
+	c.JSON(http.StatusOK, returns)
+}
+
+// AdminReturns - lists all return requests, optionally filtered by status
+func AdminReturns(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	query := config.DB.Preload("Items").Order("id desc")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var returns []models.ReturnRequest
+	if err := query.Find(&returns).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch returns"})
+		return
+	}
+	c.JSON(http.StatusOK, returns)
+}
+
+// ReviewReturn - lets an admin approve or reject a requested return
+func ReviewReturn(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	rma, ok := findReturn(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		Approve bool   `json:"approve"`
+		Note    string `json:"note"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if rma.Status != models.ReturnRequested {
+		c.JSON(http.StatusConflict, gin.H{"error": "Return has already been reviewed"})
+		return
+	}
+
+	status := models.ReturnRejected
+	if body.Approve {
+		status = models.ReturnApproved
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimReturn(tx, &rma, models.ReturnRequested, status, "Return has already been reviewed"); err != nil {
+			return err
+		}
+		rma.AdminNote = strings.TrimSpace(body.Note)
+		return tx.Model(&rma).Update("admin_note", rma.AdminNote).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to update return")
+		return
+	}
+	c.JSON(http.StatusOK, rma)
+}
+
+// ReceiveReturn - records that the returned goods arrived and puts them back in stock
+func ReceiveReturn(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	rma, ok := findReturn(c)
+	if !ok {
+		return
+	}
+	if rma.Status != models.ReturnApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only approved returns can be received"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimReturn(tx, &rma, models.ReturnApproved, models.ReturnReceived, "Only approved returns can be received"); err != nil {
+			return err
+		}
+		for _, returned := range rma.Items {
+			var line models.OrderItem
+			if err := tx.First(&line, returned.OrderItemID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&line).UpdateColumn("returned_quantity", gorm.Expr("returned_quantity + ?", returned.Quantity)).Error; err != nil {
+				return err
+			}
+			// Restock items that track stock
+			if err := tx.Model(&models.Item{}).
+				Where("id = ? AND stock IS NOT NULL", line.ItemID).
+				UpdateColumn("stock", gorm.Expr("stock + ?", returned.Quantity)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err, "Failed to receive return")
+		return
+	}
+	c.JSON(http.StatusOK, rma)
+}
+
+// RefundReturn - refunds a received return through the payment provider.
+// The admin may refund less than the calculated amount, e.g. for damage.
+func RefundReturn(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	rma, ok := findReturn(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		Amount float64 `json:"amount"`
+	}
+	if err := bindOptionalJSON(c, &body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if rma.Status != models.ReturnReceived {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only received returns can be refunded"})
+		return
+	}
+
+	amount := rma.RefundAmount
+	if body.Amount != 0 {
+		if body.Amount < 0 || body.Amount > rma.RefundAmount {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Refund must be between 0 and %.2f", rma.RefundAmount)})
+			return
+		}
+		amount = pricing.Round(body.Amount)
+	}
+
+	var order models.Order
+	if err := config.DB.First(&order, rma.OrderID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	// Claim the return before the gateway is asked, so it is refunded once
+	if err := claimReturn(config.DB, &rma, models.ReturnReceived, models.ReturnRefunded, "Only received returns can be refunded"); err != nil {
+		respondError(c, err, "Failed to issue refund")
+		return
+	}
+	payment, refund, err := issueRefund(order, amount)
+	if err != nil {
+		if undo := claimReturn(config.DB, &rma, models.ReturnRefunded, models.ReturnReceived, "Return changed meanwhile"); undo != nil {
+			log.Printf("return %d: releasing refund claim: %v", rma.ID, undo)
+		}
+		respondError(c, err, "Failed to issue refund")
+		return
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := recordRefund(tx, &payment, &order, &refund); err != nil {
+			return err
+		}
+		if order.Refunded >= order.Total {
+			if err := setOrderStatus(tx, &order, "refunded", user.Username, "Refunded in full"); err != nil {
+				return err
+			}
+		}
+		rma.RefundAmount = amount
+		return tx.Model(&rma).Update("refund_amount", rma.RefundAmount).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Refund was issued but could not be recorded"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Refund issued", "return": rma, "order": order})
+}
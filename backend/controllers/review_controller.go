@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+	"shopping-cart/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// validateReview checks a review's rating and text
+func validateReview(rating int, title, body string) string {
+	if rating < 1 || rating > 5 {
+		return "Rating must be between 1 and 5"
+	}
+	if title == "" {
+		return "Title is required"
+	}
+	if len(title) > 100 {
+		return "Title must be less than 100 characters"
+	}
+	if len(body) > 2000 {
+		return "Review must be less than 2000 characters"
+	}
+	return ""
+}
+
+// hasPurchased reports whether the user has a completed order containing the item
+func hasPurchased(db *gorm.DB, userID, itemID uint) bool {
+	var count int64
+	db.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.item_id = ?", userID, "completed", itemID).
+		Count(&count)
+	return count > 0
+}
+
+// refreshItemRating recalculates the item's rating from its approved reviews
+func refreshItemRating(tx *gorm.DB, itemID uint) error {
+	var stats struct {
+		Average float64
+		Count   int
+	}
+	if err := tx.Model(&models.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("item_id = ? AND status = ?", itemID, models.ReviewApproved).
+		Scan(&stats).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Item{}).Where("id = ?", itemID).Updates(map[string]any{
+		"rating_average": pricing.Round(stats.Average),
+		"rating_count":   stats.Count,
+	}).Error
+}
+
+// findReview loads the review named in the URL
+func findReview(c *gin.Context) (models.Review, bool) {
+	var review models.Review
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return review, false
+	}
+	if err := config.DB.First(&review, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return review, false
+	}
+	return review, true
+}
+
+// ListItemReviews - lists the approved reviews of an item, newest first
+func ListItemReviews(c *gin.Context) {
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var reviews []models.Review
+	if err := config.DB.Where("item_id = ? AND status = ?", parsedID, models.ReviewApproved).Order("id desc").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	c.JSON(http.StatusOK, reviews)
+}
+
+// CreateReview - lets a customer who received an item review it. Reviews
+// wait for moderation before they are shown.
+func CreateReview(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	var body struct {
+		Rating int    `json:"rating"`
+		Title  string `json:"title"`
+		Body   string `json:"body"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Title = strings.TrimSpace(body.Title)
+	body.Body = strings.TrimSpace(body.Body)
+	if errMsg := validateReview(body.Rating, body.Title, body.Body); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	var item models.Item
+	if err := config.DB.First(&item, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	// Only verified buyers can review
+	if !hasPurchased(config.DB, user.ID, item.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only customers who received this item can review it"})
+		return
+	}
+
+	var existing models.Review
+	if err := config.DB.Where("item_id = ? AND user_id = ?", item.ID, user.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this item"})
+		return
+	}
+
+	review := models.Review{
+		ItemID:           item.ID,
+		UserID:           user.ID,
+		Author:           user.Username,
+		Rating:           body.Rating,
+		Title:            body.Title,
+		Body:             body.Body,
+		VerifiedPurchase: true,
+		Status:           models.ReviewPending,
+	}
+	if err := config.DB.Create(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Review submitted for moderation", "review": review})
+}
+
+// ModerationQueue - lists reviews waiting for moderation, or with ?status= another status
+func ModerationQueue(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.ReviewPending)
+	var reviews []models.Review
+	if err := config.DB.Where("status = ?", status).Order("id").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	c.JSON(http.StatusOK, reviews)
+}
+
+// ModerateReview - lets admin approve or reject a review
+func ModerateReview(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	review, ok := findReview(c)
+	if !ok {
+		return
+	}
+	var body struct {
+		Approve bool `json:"approve"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	review.Status = models.ReviewRejected
+	if body.Approve {
+		review.Status = models.ReviewApproved
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&review).Update("status", review.Status).Error; err != nil {
+			return err
+		}
+		return refreshItemRating(tx, review.ItemID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate review"})
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview - lets the author or admin delete a review
+func DeleteReview(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	review, ok := findReview(c)
+	if !ok {
+		return
+	}
+	if review.UserID != user.ID && !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return refreshItemRating(tx, review.ItemID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted"})
+}
@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/middleware"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// serviceAccountPrefix starts the usernames of service accounts' users
+const serviceAccountPrefix = "svc_"
+
+var serviceAccountName = regexp.MustCompile(`^[a-z0-9_]{3,26}$`)
+
+// apiKeyResponse is an API key without the key itself
+type apiKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(key models.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     strings.Fields(key.Scopes),
+		ExpiresAt:  key.ExpiresAt,
+		RevokedAt:  key.RevokedAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// serviceAccountResponse is a service account with its keys
+type serviceAccountResponse struct {
+	ID          uint             `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Username    string           `json:"username"`
+	Disabled    bool             `json:"disabled"`
+	CreatedBy   string           `json:"created_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Keys        []apiKeyResponse `json:"keys"`
+}
+
+func newServiceAccountResponse(account models.ServiceAccount) serviceAccountResponse {
+	response := serviceAccountResponse{
+		ID:          account.ID,
+		Name:        account.Name,
+		Description: account.Description,
+		CreatedBy:   account.CreatedBy,
+		CreatedAt:   account.CreatedAt,
+		Keys:        make([]apiKeyResponse, 0, len(account.Keys)),
+	}
+	if account.User != nil {
+		response.Username = account.User.Username
+		response.Disabled = account.User.Disabled
+	}
+	for _, key := range account.Keys {
+		response.Keys = append(response.Keys, newAPIKeyResponse(key))
+	}
+	return response
+}
+
+// validateScopes checks that every scope exists and returns them sorted
+// without duplicates
+func validateScopes(scopes []string) ([]string, string) {
+	if len(scopes) == 0 {
+		return nil, "At least one scope is required"
+	}
+	for _, scope := range scopes {
+		if _, ok := middleware.APIScopes[scope]; !ok {
+			return nil, "Unknown scope: " + scope
+		}
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	return slices.Compact(scopes), ""
+}
+
+// createAPIKey adds a key to the service account and returns it. The key
+// itself is only ever returned here.
+func createAPIKey(tx *gorm.DB, accountID uint, name string, scopes []string, expiresAt *time.Time) (string, models.APIKey, error) {
+	secret, _, err := newSecretToken()
+	if err != nil {
+		return "", models.APIKey{}, err
+	}
+	token := middleware.APIKeyPrefix + secret
+	key := models.APIKey{
+		ServiceAccountID: accountID,
+		Name:             name,
+		Prefix:           token[:len(middleware.APIKeyPrefix)+8],
+		KeyHash:          hashSecretToken(token),
+		Scopes:           strings.Join(scopes, " "),
+		ExpiresAt:        expiresAt,
+	}
+	err = tx.Create(&key).Error
+	return token, key, err
+}
+
+// findAPIKey loads the API key named in the URL with its service account
+func findAPIKey(c *gin.Context) (models.APIKey, bool) {
+	var key models.APIKey
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return key, false
+	}
+	if err := config.DB.Preload("ServiceAccount").First(&key, parsedID).Error; err != nil || key.ServiceAccount == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return key, false
+	}
+	return key, true
+}
+
+// ListServiceAccounts - lets admin see the service accounts and their keys
+func ListServiceAccounts(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+
+	var accounts []models.ServiceAccount
+	if err := config.DB.Preload("User", withoutSecrets).Preload("Keys", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Order("name").Find(&accounts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch service accounts"})
+		return
+	}
+	results := make([]serviceAccountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		results = append(results, newServiceAccountResponse(account))
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// CreateServiceAccount - lets admin add a service account for an
+// integration. It gets a user that cannot log in, named svc_<name>.
+func CreateServiceAccount(c *gin.Context) {
+	admin := c.MustGet("user").(models.User)
+	if !admin.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var body struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if !serviceAccountName.MatchString(body.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must be 3 to 26 lowercase letters, numbers or underscores"})
+		return
+	}
+
+	account := models.ServiceAccount{Name: body.Name, Description: strings.TrimSpace(body.Description), CreatedBy: admin.Username}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Model(&models.ServiceAccount{}).Where("name = ?", account.Name).Count(&count)
+		if count > 0 {
+			return &apiError{http.StatusConflict, "Service account already exists"}
+		}
+		user := models.User{Username: serviceAccountPrefix + account.Name}
+		tx.Model(&models.User{}).Where("username = ?", user.Username).Count(&count)
+		if count > 0 {
+			return &apiError{http.StatusConflict, "Username " + user.Username + " is already taken"}
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		account.UserID = user.ID
+		account.User = &user
+		return tx.Create(&account).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create service account")
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "service_account.created", "service:"+account.Name, "")
+	c.JSON(http.StatusCreated, newServiceAccountResponse(account))
+}
+
+// CreateAPIKey - lets admin issue an API key for a service account with
+// the given scopes, optionally expiring after expires_in_days. The key is
+// shown only in this response.
+func CreateAPIKey(c *gin.Context) {
+	admin := c.MustGet("user").(models.User)
+	if !admin.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service account ID"})
+		return
+	}
+	var body struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+	if body.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days cannot be negative"})
+		return
+	}
+	scopes, errMsg := validateScopes(body.Scopes)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	var account models.ServiceAccount
+	if err := config.DB.First(&account, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return
+	}
+
+	var expiresAt *time.Time
+	if body.ExpiresInDays > 0 {
+		expiry := time.Now().AddDate(0, 0, body.ExpiresInDays)
+		expiresAt = &expiry
+	}
+	token, key, err := createAPIKey(config.DB, account.ID, body.Name, scopes, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "apikey.created", "service:"+account.Name, key.Prefix+" "+key.Scopes)
+	c.JSON(http.StatusCreated, gin.H{"key": token, "api_key": newAPIKeyResponse(key)})
+}
+
+// RotateAPIKey - lets admin replace an API key with a new one with the
+// same scopes. The old key keeps working for the rotation grace period.
+func RotateAPIKey(c *gin.Context) {
+	admin := c.MustGet("user").(models.User)
+	if !admin.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	key, ok := findAPIKey(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only active API keys can be rotated"})
+		return
+	}
+
+	// The new key lives as long as the old one was meant to
+	var expiresAt *time.Time
+	if key.ExpiresAt != nil {
+		expiry := now.Add(key.ExpiresAt.Sub(key.CreatedAt))
+		expiresAt = &expiry
+	}
+	oldExpiry := now.Add(config.APIKeyRotationGrace)
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(oldExpiry) {
+		oldExpiry = *key.ExpiresAt
+	}
+
+	var token string
+	var rotated models.APIKey
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&key).Update("expires_at", oldExpiry).Error; err != nil {
+			return err
+		}
+		var err error
+		token, rotated, err = createAPIKey(tx, key.ServiceAccountID, key.Name, strings.Fields(key.Scopes), expiresAt)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate API key"})
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "apikey.rotated", "service:"+key.ServiceAccount.Name, key.Prefix+" replaced by "+rotated.Prefix)
+	c.JSON(http.StatusCreated, gin.H{"key": token, "api_key": newAPIKeyResponse(rotated), "old_key_expires_at": oldExpiry})
+}
+
+// RevokeAPIKey - lets admin stop an API key from working at once
+func RevokeAPIKey(c *gin.Context) {
+	admin := c.MustGet("user").(models.User)
+	if !admin.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	key, ok := findAPIKey(c)
+	if !ok {
+		return
+	}
+	if key.RevokedAt != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked", "api_key": newAPIKeyResponse(key)})
+		return
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := config.DB.Model(&key).Update("revoked_at", now).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	recordAudit(config.DB, c, admin.Username, "apikey.revoked", "service:"+key.ServiceAccount.Name, key.Prefix)
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked", "api_key": newAPIKeyResponse(key)})
+}
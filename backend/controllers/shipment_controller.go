@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// shipmentLine is a quantity of an order line to put in a shipment
+type shipmentLine struct {
+	OrderItemID uint `json:"order_item_id"`
+	Quantity    int  `json:"quantity"`
+}
+
+// fulfillmentStatus is the order status that matches how much of the
+// order has shipped
+func fulfillmentStatus(lines []models.OrderItem) string {
+	for _, line := range lines {
+		if line.ShippedQuantity < line.Quantity {
+			return "partially_shipped"
+		}
+	}
+	return "shipped"
+}
+
+// createShipment records a parcel leaving with the given lines of the order,
+// or with everything not shipped yet when no lines are given. The payment is
+// captured before the first shipment is recorded, so a declined capture
+// records nothing, and the order status follows how much has shipped. The
+// order must be loaded with its items.
+func createShipment(order *models.Order, carrier, trackingCode string, lines []shipmentLine, actor string) (models.Shipment, error) {
+	shipment := models.Shipment{OrderID: order.ID, Carrier: carrier, TrackingCode: trackingCode}
+	if order.Status != "processing" && order.Status != "partially_shipped" {
+		return shipment, &apiError{http.StatusConflict, "Only paid orders that have not fully shipped can be shipped"}
+	}
+
+	remaining := map[uint]int{}
+	for _, line := range order.Items {
+		remaining[line.ID] = line.Quantity - line.ShippedQuantity
+	}
+	if len(lines) == 0 {
+		for _, line := range order.Items {
+			if remaining[line.ID] > 0 {
+				lines = append(lines, shipmentLine{OrderItemID: line.ID, Quantity: remaining[line.ID]})
+			}
+		}
+	}
+	for _, line := range lines {
+		left, ok := remaining[line.OrderItemID]
+		if !ok {
+			return shipment, &apiError{http.StatusBadRequest, "Item is not part of this order"}
+		}
+		if line.Quantity <= 0 || line.Quantity > left {
+			return shipment, &apiError{http.StatusBadRequest, fmt.Sprintf("Only %d of order item %d are left to ship", left, line.OrderItemID)}
+		}
+		remaining[line.OrderItemID] -= line.Quantity
+		shipment.Items = append(shipment.Items, models.ShipmentItem{OrderItemID: line.OrderItemID, Quantity: line.Quantity})
+	}
+	if len(shipment.Items) == 0 {
+		return shipment, &apiError{http.StatusConflict, "Nothing is left to ship"}
+	}
+
+	// Take the money once the first goods are on their way. The capture is
+	// saved on its own, so a shipment that fails below cannot roll back money
+	// the gateway already took, and the next shipment does not capture again.
+	if err := captureOrderPayment(*order); err != nil {
+		return shipment, err
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Hold the order in the status it was loaded with, so a cancellation
+		// or another shipment that got there first stops this one
+		result := tx.Model(&models.Order{}).Where("id = ? AND status = ?", order.ID, order.Status).Update("status", order.Status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return &apiError{http.StatusConflict, "Order was changed by another request, try again"}
+		}
+
+		if err := tx.Create(&shipment).Error; err != nil {
+			return err
+		}
+		for _, item := range shipment.Items {
+			result := tx.Model(&models.OrderItem{}).
+				Where("id = ? AND shipped_quantity + ? <= quantity", item.OrderItemID, item.Quantity).
+				UpdateColumn("shipped_quantity", gorm.Expr("shipped_quantity + ?", item.Quantity))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != 1 {
+				return &apiError{http.StatusConflict, fmt.Sprintf("Order item %d was shipped by another request", item.OrderItemID)}
+			}
+		}
+		if err := tx.Where("order_id = ?", order.ID).Order("id").Find(&order.Items).Error; err != nil {
+			return err
+		}
+
+		status := fulfillmentStatus(order.Items)
+		if status == order.Status {
+			return nil
+		}
+		note := "Shipped"
+		if carrier != "" {
+			note = strings.TrimSpace(fmt.Sprintf("Shipped with %s %s", carrier, trackingCode))
+		}
+		return setOrderStatus(tx, order, status, actor, note)
+	})
+	return shipment, err
+}
+
+// CreateShipment - lets admin ship some or all of an order's remaining items
+func CreateShipment(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var body struct {
+		Carrier      string         `json:"carrier"`
+		TrackingCode string         `json:"tracking_code"`
+		Items        []shipmentLine `json:"items"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	body.Carrier = strings.TrimSpace(body.Carrier)
+	body.TrackingCode = strings.TrimSpace(body.TrackingCode)
+	if body.Carrier == "" || body.TrackingCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Carrier and tracking code are required"})
+		return
+	}
+
+	var order models.Order
+	if err := orderByParam(c, config.DB.Preload("Items"), user).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	shipment, err := createShipment(&order, body.Carrier, body.TrackingCode, body.Items, user.Username)
+	if err != nil {
+		respondError(c, err, "Failed to create shipment")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Shipment created", "shipment": shipment, "order": order})
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+	"shopping-cart/pricing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validateShippingMethod checks that a shipping method can be priced
+func validateShippingMethod(method models.ShippingMethod) string {
+	if method.Code == "" {
+		return "Shipping method code is required"
+	}
+	if method.Name == "" {
+		return "Shipping method name is required"
+	}
+	if method.Type != models.ShippingFlat && method.Type != models.ShippingWeight {
+		return "Shipping type must be flat or weight"
+	}
+	if method.BaseRate < 0 || method.PerKg < 0 || method.FreeOver < 0 {
+		return "Shipping rates cannot be negative"
+	}
+	return ""
+}
+
+// QuoteShipping - lists the shipping methods available for the current cart and address
+func QuoteShipping(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+
+	var addressID uint64
+	if id := c.Query("address_id"); id != "" {
+		parsedID, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid address ID"})
+			return
+		}
+		addressID = parsedID
+	}
+	address, err := findShippingAddress(config.DB, user.ID, uint(addressID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Shipping address not found"})
+		return
+	}
+	shipTo := address.Snapshot()
+
+	cart, err := findOrCreateCart(config.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart"})
+		return
+	}
+	priced, err := priceCart(config.DB, cart, user.ID, shipTo.TaxRegion())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart items"})
+		return
+	}
+
+	var methods []models.ShippingMethod
+	config.DB.Where("disabled = ?", false).Order("id").Find(&methods)
+
+	options := []pricing.ShippingOption{}
+	for _, method := range methods {
+		if option, errMsg := priced.Quote.ShippingOptionFor(method, shipTo.Country); errMsg == "" {
+			options = append(options, option)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"address_id": address.ID,
+		"weight":     priced.Quote.Weight(),
+		"methods":    options,
+	})
+}
+
+func CreateShippingMethod(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var method models.ShippingMethod
+	if err := c.BindJSON(&method); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	method.ID = 0
+	method.Code = strings.ToLower(strings.TrimSpace(method.Code))
+	for i, country := range method.Countries {
+		method.Countries[i] = strings.ToUpper(strings.TrimSpace(country))
+	}
+
+	if errMsg := validateShippingMethod(method); errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
+		return
+	}
+
+	var existing models.ShippingMethod
+	if err := config.DB.Where("code = ?", method.Code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Shipping method code already exists"})
+		return
+	}
+
+	if err := config.DB.Create(&method).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create shipping method"})
+		return
+	}
+	c.JSON(http.StatusCreated, method)
+}
+
+func ListShippingMethods(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var methods []models.ShippingMethod
+	config.DB.Order("id").Find(&methods)
+	c.JSON(http.StatusOK, methods)
+}
+
+func DeleteShippingMethod(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid shipping method ID"})
+		return
+	}
+
+	// Orders keep the method name and cost they were charged
+	if err := config.DB.Delete(&models.ShippingMethod{}, parsedID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete shipping method"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Shipping method deleted"})
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+	"shopping-cart/pricing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// newTaxCalculator builds the calculator used to tax carts and orders.
+// It is a variable so another pricing.TaxCalculator can be plugged in.
+var newTaxCalculator = func(db *gorm.DB) (pricing.TaxCalculator, error) {
+	var rates []models.TaxRate
+	if err := db.Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return pricing.RateTable(rates), nil
+}
+
+// normalizeRegion makes region codes case-insensitive
+func normalizeRegion(region string) string {
+	return strings.ToUpper(strings.TrimSpace(region))
+}
+
+func CreateTaxRate(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var rate models.TaxRate
+	if err := c.BindJSON(&rate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	rate.ID = 0
+	rate.Region = normalizeRegion(rate.Region)
+	rate.TaxClass = strings.TrimSpace(rate.TaxClass)
+	if rate.TaxClass == "" {
+		rate.TaxClass = models.TaxClassStandard
+	}
+
+	if rate.Region == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Region is required"})
+		return
+	}
+	if rate.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tax name is required"})
+		return
+	}
+	if rate.Rate < 0 || rate.Rate > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rate must be between 0 and 100 percent"})
+		return
+	}
+
+	if err := config.DB.Create(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tax rate"})
+		return
+	}
+	c.JSON(http.StatusCreated, rate)
+}
+
+func ListTaxRates(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	var rates []models.TaxRate
+	config.DB.Order("region, tax_class, id").Find(&rates)
+	c.JSON(http.StatusOK, rates)
+}
+
+func DeleteTaxRate(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if !user.Admin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+		return
+	}
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tax rate ID"})
+		return
+	}
+
+	// Orders keep their own tax breakdown, so removing a rate is safe
+	if err := config.DB.Delete(&models.TaxRate{}, parsedID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tax rate"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tax rate deleted"})
+}
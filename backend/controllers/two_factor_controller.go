@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+	"shopping-cart/totp"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is how many recovery codes a user gets at a time
+const recoveryCodeCount = 10
+
+// findTwoFactor returns the user's enabled two-factor settings
+func findTwoFactor(db *gorm.DB, userID uint) (models.TwoFactor, bool) {
+	var tf models.TwoFactor
+	err := db.Where("user_id = ? AND enabled = ?", userID, true).First(&tf).Error
+	return tf, err == nil
+}
+
+// normalizeRecoveryCode strips the dashes and spaces users type with a code
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// newRecoveryCodes replaces the user's recovery codes and returns the new
+// ones, formatted as XXXX-XXXX-XXXX-XXXX
+func newRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := base32.StdEncoding.EncodeToString(b)
+		if err := tx.Create(&models.RecoveryCode{UserID: userID, CodeHash: hashSecretToken(raw)}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+	}
+	return codes, nil
+}
+
+// checkSecondFactor accepts a current TOTP code that has not been used yet,
+// or an unused recovery code, and uses it up
+func checkSecondFactor(tx *gorm.DB, tf models.TwoFactor, code string) (bool, error) {
+	if step, ok := totp.Validate(tf.Secret, code, time.Now()); ok {
+		used := tx.Model(&models.TwoFactor{}).Where("id = ? AND last_step < ?", tf.ID, step).Update("last_step", step)
+		return used.RowsAffected == 1, used.Error
+	}
+
+	var recovery models.RecoveryCode
+	if err := tx.Where("user_id = ? AND code_hash = ? AND used_at IS NULL", tf.UserID, hashSecretToken(normalizeRecoveryCode(code))).First(&recovery).Error; err != nil {
+		return false, nil
+	}
+	used := tx.Model(&recovery).Where("used_at IS NULL").Update("used_at", time.Now())
+	return used.RowsAffected == 1, used.Error
+}
+
+// TwoFactorStatus - shows whether two-factor authentication is on for the user
+func TwoFactorStatus(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	_, enabled := findTwoFactor(config.DB, user.ID)
+
+	var left int64
+	config.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&left)
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":             enabled,
+		"required":            user.Admin && config.RequireAdmin2FA,
+		"recovery_codes_left": left,
+	})
+}
+
+// EnrollTwoFactor - creates a TOTP secret for the user to add to an
+// authenticator app. It takes effect once confirmed with VerifyTwoFactor.
+func EnrollTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if _, enabled := findTwoFactor(config.DB, user.ID); enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create secret"})
+		return
+	}
+
+	// Enrolling again replaces a secret that was never confirmed
+	var tf models.TwoFactor
+	config.DB.Where("user_id = ?", user.ID).First(&tf)
+	tf.UserID = user.ID
+	tf.Secret = secret
+	tf.LastStep = 0
+	if err := config.DB.Save(&tf).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.URI(config.TOTPIssuer, user.Username, secret),
+	})
+}
+
+// VerifyTwoFactor - turns on two-factor authentication with a first code
+// from the authenticator app and returns the recovery codes, which are
+// only shown this once
+func VerifyTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var tf models.TwoFactor
+	if err := config.DB.Where("user_id = ?", user.ID).First(&tf).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start two-factor enrollment first"})
+		return
+	}
+	if tf.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	step, ok := totp.Validate(tf.Secret, body.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&tf).Updates(map[string]any{"enabled": true, "last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = newRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes - replaces the user's recovery codes, given a
+// current code
+func RegenerateRecoveryCodes(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	tf, enabled := findTwoFactor(config.DB, user.ID)
+	if !enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		ok, err := checkSecondFactor(tx, tf, body.Code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &apiError{http.StatusBadRequest, "Invalid code"}
+		}
+		codes, err = newRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		respondError(c, err, "Failed to create recovery codes")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor - turns off two-factor authentication, given the
+// password and a current code
+func DisableTwoFactor(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if user.Admin && config.RequireAdmin2FA {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for admin accounts"})
+		return
+	}
+	var body struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	tf, enabled := findTwoFactor(config.DB, user.ID)
+	if !enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		ok, err := checkSecondFactor(tx, tf, body.Code)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &apiError{http.StatusBadRequest, "Invalid code"}
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tf).Error
+	})
+	if err != nil {
+		respondError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// LoginTwoFactor - finishes a login with the challenge token from Login and
+// a TOTP or recovery code
+func LoginTwoFactor(c *gin.Context) {
+	var body struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var challenge models.LoginChallenge
+	if err := config.DB.Where("token_hash = ? AND expires_at > ?", hashSecretToken(body.ChallengeToken), time.Now()).First(&challenge).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login has expired, please log in again"})
+		return
+	}
+	var user models.User
+	if err := config.DB.First(&user, challenge.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login has expired, please log in again"})
+		return
+	}
+	tf, enabled := findTwoFactor(config.DB, user.ID)
+	if !enabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login has expired, please log in again"})
+		return
+	}
+	if !checkLoginLimits(c, user.Username) {
+		return
+	}
+
+	ok, err := checkSecondFactor(config.DB, tf, body.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check code"})
+		return
+	}
+	if !ok {
+		// A challenge allows a few tries before the password is needed again,
+		// and wrong codes count towards the account lockout as well
+		loginFailed(c, user.Username)
+		challenge.Attempts++
+		if challenge.Attempts >= 5 {
+			config.DB.Delete(&challenge)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Too many invalid codes, please log in again"})
+			return
+		}
+		config.DB.Model(&challenge).Update("attempts", challenge.Attempts)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return
+	}
+
+	config.DB.Delete(&challenge)
+	loginSucceeded(user.Username)
+	startSession(c, user, true)
+}
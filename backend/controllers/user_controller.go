@@ -1,27 +1,31 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 	"regexp"
 	"strings"
+	"time"
 
 	"shopping-cart/config"
 	"shopping-cart/models"
+	"shopping-cart/passwordpolicy"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// validatePassword checks if password meets security requirements
-func validatePassword(password string) string {
-	if len(password) < 6 {
-		return "Password must be at least 6 characters"
+// validatePassword checks a new password against the password policy. On
+// failure it responds with the first problem as the error and every broken
+// rule under "failures", and returns false.
+func validatePassword(c *gin.Context, password, username string) bool {
+	failures := passwordpolicy.Default.Check(password, username)
+	if len(failures) == 0 {
+		return true
 	}
-	if len(password) > 50 {
-		return "Password must be less than 50 characters"
-	}
-	return ""
+	c.JSON(http.StatusBadRequest, gin.H{"error": failures[0].Message, "failures": failures})
+	return false
 }
 
 // validateUsername checks if username is valid
@@ -41,12 +45,17 @@ func validateUsername(username string) string {
 }
 
 func Register(c *gin.Context) {
-	var user models.User
-	c.BindJSON(&user)
+	var body struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	c.BindJSON(&body)
 
-	// Trim whitespace
-	user.Username = strings.TrimSpace(user.Username)
-	user.Password = strings.TrimSpace(user.Password)
+	// Only these fields come from the client; roles are given by admins.
+	// Trim whitespace; passwords are taken exactly as typed
+	email := normalizeEmail(body.Email)
+	user := models.User{Username: strings.TrimSpace(body.Username), Email: &email, Password: body.Password}
 
 	// Validate input
 	if user.Username == "" {
@@ -65,7 +74,12 @@ func Register(c *gin.Context) {
 	}
 
 	// Validate password strength
-	if errMsg := validatePassword(user.Password); errMsg != "" {
+	if !validatePassword(c, user.Password, user.Username) {
+		return
+	}
+
+	// Validate email format
+	if errMsg := validateEmail(email); errMsg != "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
 		return
 	}
@@ -77,6 +91,12 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// Check if email already exists
+	if emailTaken(config.DB, email, 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -91,55 +111,82 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Don't return password in response
-	user.Password = ""
-	c.JSON(http.StatusCreated, user)
+	// The account works without the email, so a failed send is only logged;
+	// the user can ask for the link again
+	if err := sendVerificationEmail(config.DB, user); err != nil {
+		log.Printf("verification mail for user %d: %v", user.ID, err)
+	}
+
+	// Don't return password or token in response
+	c.JSON(http.StatusCreated, newProfileResponse(user))
 }
 
 func Login(c *gin.Context) {
 	var body models.User
 	c.BindJSON(&body)
 
-	// Trim whitespace
+	// Trim whitespace; passwords are taken exactly as typed
 	body.Username = strings.TrimSpace(body.Username)
-	body.Password = strings.TrimSpace(body.Password)
+
+	// Back off repeated failures
+	if !checkLoginLimits(c, body.Username) {
+		return
+	}
 
 	// Find user
 	var user models.User
 	if err := config.DB.Where("username = ?", body.Username).First(&user).Error; err != nil {
+		loginFailed(c, body.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password)); err != nil {
+		loginFailed(c, body.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	// Generate token
-	token := uuid.NewString()
-	user.Token = token
-	config.DB.Save(&user)
-
-	c.JSON(http.StatusOK, gin.H{"token": token, "user_id": user.ID, "username": user.Username, "admin": user.Admin})
-}
+	// Disabled accounts are told so only once the password is right
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
 
-func ListUsers(c *gin.Context) {
-	// Check if admin
-	user := c.MustGet("user").(models.User)
-	if !user.Admin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin only"})
+	// With two-factor authentication the password only earns a challenge,
+	// answered with a code at /users/login/2fa
+	if _, enabled := findTwoFactor(config.DB, user.ID); enabled {
+		config.DB.Where("expires_at < ?", time.Now()).Delete(&models.LoginChallenge{})
+		challengeToken, hash, err := newSecretToken()
+		if err == nil {
+			err = config.DB.Create(&models.LoginChallenge{UserID: user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(config.LoginChallengeTTL)}).Error
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge_token": challengeToken})
 		return
 	}
 
-	var users []models.User
-	config.DB.Find(&users)
+	loginSucceeded(user.Username)
+	startSession(c, user, false)
+}
 
-	// Don't return passwords
-	for i := range users {
-		users[i].Password = ""
-	}
+// startSession logs the user in with a new token
+func startSession(c *gin.Context, user models.User, twoFactor bool) {
+	// Generate token
+	token := uuid.NewString()
+	user.Token = token
+	config.DB.Save(&user)
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, gin.H{
+		"token":                     token,
+		"user_id":                   user.ID,
+		"username":                  user.Username,
+		"admin":                     user.Admin,
+		"email_verified":            user.EmailVerifiedAt != nil,
+		"two_factor_setup_required": user.Admin && config.RequireAdmin2FA && !twoFactor,
+	})
 }
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// findOrCreateWishlist returns the user's wishlist, creating it on first use
+func findOrCreateWishlist(db *gorm.DB, userID uint) (models.Wishlist, error) {
+	var wishlist models.Wishlist
+	err := db.Where(models.Wishlist{UserID: userID}).FirstOrCreate(&wishlist).Error
+	return wishlist, err
+}
+
+// findWishlistItem loads a wishlist item from the URL that belongs to the user
+func findWishlistItem(c *gin.Context, userID uint) (models.WishlistItem, bool) {
+	var wishlistItem models.WishlistItem
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wishlist item ID"})
+		return wishlistItem, false
+	}
+	if err := config.DB.First(&wishlistItem, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist item not found"})
+		return wishlistItem, false
+	}
+
+	var wishlist models.Wishlist
+	if err := config.DB.First(&wishlist, wishlistItem.WishlistID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return wishlistItem, false
+	}
+	if wishlist.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return wishlistItem, false
+	}
+	return wishlistItem, true
+}
+
+func ListWishlist(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	wishlist, err := findOrCreateWishlist(config.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wishlist"})
+		return
+	}
+
+	var items []models.WishlistItem
+	if err := config.DB.Preload("Item").Where("wishlist_id = ?", wishlist.ID).Order("id").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wishlist items"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "share_token": wishlist.ShareToken})
+}
+
+func AddToWishlist(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	var body struct {
+		ItemID uint
+	}
+	if err := c.BindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	var item models.Item
+	if err := config.DB.First(&item, body.ItemID).Error; err != nil || item.Archived {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	wishlist, err := findOrCreateWishlist(config.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wishlist"})
+		return
+	}
+
+	// Adding an item twice keeps the one entry
+	var wishlistItem models.WishlistItem
+	if err := config.DB.Where("wishlist_id = ? AND item_id = ?", wishlist.ID, item.ID).First(&wishlistItem).Error; err == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Already on wishlist", "wishlist_item": wishlistItem})
+		return
+	}
+
+	wishlistItem = models.WishlistItem{WishlistID: wishlist.ID, ItemID: item.ID, Quantity: 1}
+	if err := config.DB.Create(&wishlistItem).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to wishlist"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Added to wishlist", "wishlist_item": wishlistItem})
+}
+
+func RemoveFromWishlist(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	wishlistItem, ok := findWishlistItem(c, user.ID)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&wishlistItem).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove wishlist item"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Wishlist item removed"})
+}
+
+// SaveForLater - moves a cart item to the wishlist, keeping its quantity
+func SaveForLater(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	parsedID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item ID"})
+		return
+	}
+
+	var cartItem models.CartItem
+	if err := config.DB.First(&cartItem, parsedID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart item not found"})
+		return
+	}
+
+	var cart models.Cart
+	if err := config.DB.First(&cart, cartItem.CartID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found"})
+		return
+	}
+
+	if cart.UserID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var wishlistItem models.WishlistItem
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		wishlist, err := findOrCreateWishlist(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("wishlist_id = ? AND item_id = ?", wishlist.ID, cartItem.ItemID).First(&wishlistItem).Error; err != nil {
+			wishlistItem = models.WishlistItem{WishlistID: wishlist.ID, ItemID: cartItem.ItemID}
+		}
+		wishlistItem.Quantity = cartItem.Quantity
+		if err := tx.Save(&wishlistItem).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&cartItem).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save for later"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Saved for later", "wishlist_item": wishlistItem})
+}
+
+// MoveToCart - moves a wishlist item back into the cart
+func MoveToCart(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	wishlistItem, ok := findWishlistItem(c, user.ID)
+	if !ok {
+		return
+	}
+
+	var item models.Item
+	if err := config.DB.First(&item, wishlistItem.ItemID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	var cartItem models.CartItem
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		cart, err := findOrCreateCart(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		if cartItem, err = addItemToCart(tx, cart, item, max(wishlistItem.Quantity, 1)); err != nil {
+			return err
+		}
+		return tx.Delete(&wishlistItem).Error
+	})
+	if err != nil {
+		var stockErr *stockError
+		if errors.As(err, &stockErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Not enough stock", "available": stockErr.Available})
+			return
+		}
+		respondError(c, err, "Failed to move to cart")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Moved to cart", "cart_item": cartItem})
+}
+
+// ShareWishlist - creates a read-only link to the user's wishlist
+func ShareWishlist(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	wishlist, err := findOrCreateWishlist(config.DB, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get wishlist"})
+		return
+	}
+
+	// Sharing again keeps the link that was already handed out
+	if wishlist.ShareToken == nil {
+		token := uuid.NewString()
+		if err := config.DB.Model(&wishlist).Update("share_token", token).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share wishlist"})
+			return
+		}
+		wishlist.ShareToken = &token
+	}
+	c.JSON(http.StatusOK, gin.H{"share_token": *wishlist.ShareToken, "url": "/wishlists/shared/" + *wishlist.ShareToken})
+}
+
+// UnshareWishlist - turns off the wishlist's shared link
+func UnshareWishlist(c *gin.Context) {
+	user := c.MustGet("user").(models.User)
+	if err := config.DB.Model(&models.Wishlist{}).Where("user_id = ?", user.ID).Update("share_token", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop sharing wishlist"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Wishlist is no longer shared"})
+}
+
+// SharedWishlist - shows a shared wishlist to anyone with its link
+func SharedWishlist(c *gin.Context) {
+	var wishlist models.Wishlist
+	if err := config.DB.Preload("Items.Item").Where("share_token = ?", c.Param("token")).First(&wishlist).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wishlist not found"})
+		return
+	}
+
+	// Only the items are shown, nothing about the owner
+	c.JSON(http.StatusOK, gin.H{"items": wishlist.Items})
+}
@@ -0,0 +1,63 @@
+package invoice
+
+import (
+	"bytes"
+	"html/template"
+
+	"shopping-cart/models"
+)
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money":   Money,
+	"address": addressLines,
+	"date":    func(inv models.Invoice) string { return inv.IssuedAt.Format(dateLayout) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Inv.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; margin: 40px; }
+h1 { margin: 0 0 4px; }
+.meta, .addresses { margin-bottom: 24px; }
+.addresses { display: flex; gap: 80px; }
+.addresses h3 { margin: 0 0 4px; font-size: 13px; text-transform: uppercase; color: #666; }
+table { width: 100%; border-collapse: collapse; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.summary { width: 320px; margin-left: auto; margin-top: 16px; }
+.total td { font-weight: bold; border-top: 2px solid #222; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Inv.Number}}</h1>
+<div class="meta">
+Issued {{date .Inv}}<br>
+Order {{.Inv.OrderNumber}}
+</div>
+<div class="addresses">
+<div><h3>Bill to</h3>{{range address .Inv.BillingAddress}}{{.}}<br>{{end}}</div>
+<div><h3>Ship to</h3>{{range address .Inv.ShippingAddress}}{{.}}<br>{{end}}</div>
+</div>
+<table>
+<tr><th>Description</th><th>Qty</th><th>Unit price</th><th>Discount</th><th>Tax</th><th>Total</th></tr>
+{{range .Inv.Lines}}<tr><td>{{.Description}}</td><td>{{.Quantity}}</td><td>{{money .UnitPrice}}</td><td>{{money .Discount}}</td><td>{{money .Tax}}</td><td>{{money .Total}}</td></tr>
+{{end}}</table>
+<table class="summary">
+{{range .Summary}}<tr><td>{{.Description}}</td><td>{{money .Amount}}</td></tr>
+{{end}}<tr class="total"><td>Total</td><td>{{money .Inv.Total}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// HTML renders the invoice as a standalone printable web page
+func HTML(inv models.Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, struct {
+		Inv     models.Invoice
+		Summary []models.InvoiceCharge
+	}{inv, summary(inv)})
+	return buf.Bytes(), err
+}
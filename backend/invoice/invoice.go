@@ -0,0 +1,73 @@
+// Package invoice renders issued invoices as printable PDF and HTML
+// documents. It only reads the invoice snapshot, never the live order.
+package invoice
+
+import (
+	"fmt"
+	"strings"
+
+	"shopping-cart/models"
+	"shopping-cart/pricing"
+)
+
+// dateLayout is how dates are printed on invoices
+const dateLayout = "January 2, 2006"
+
+// Money formats an amount in US dollars, e.g. $1,234.50 or -$5.00
+func Money(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	whole := fmt.Sprintf("%.2f", amount)
+	digits, cents := whole[:len(whole)-3], whole[len(whole)-3:]
+
+	var grouped strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + "$" + grouped.String() + cents
+}
+
+// addressLines lays an address out the way it is written on an envelope
+func addressLines(address models.AddressSnapshot) []string {
+	lines := []string{address.Name, address.Line1}
+	if address.Line2 != "" {
+		lines = append(lines, address.Line2)
+	}
+	city := address.City
+	if address.Region != "" {
+		city += ", " + address.Region
+	}
+	if address.PostalCode != "" {
+		city += " " + address.PostalCode
+	}
+	lines = append(lines, city, address.Country)
+	if address.Phone != "" {
+		lines = append(lines, address.Phone)
+	}
+	return lines
+}
+
+// summary lists the totals printed under the invoice lines
+func summary(inv models.Invoice) []models.InvoiceCharge {
+	rows := []models.InvoiceCharge{{Description: "Subtotal", Amount: inv.Subtotal}}
+	for _, adj := range inv.Adjustments {
+		rows = append(rows, models.InvoiceCharge{Description: adj.Description, Amount: -adj.Amount})
+	}
+	if inv.ShippingMethod != "" {
+		rows = append(rows, models.InvoiceCharge{Description: "Shipping (" + inv.ShippingMethod + ")", Amount: inv.Shipping})
+	}
+	for _, tax := range inv.Taxes {
+		description := tax.Description
+		if inv.TaxMode == pricing.TaxInclusive {
+			description += " (included)"
+		}
+		rows = append(rows, models.InvoiceCharge{Description: description, Amount: tax.Amount})
+	}
+	return rows
+}
@@ -0,0 +1,247 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"shopping-cart/models"
+)
+
+// A4 page in points, the unit PDF measures in
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	margin     = 50
+	right      = pageWidth - margin
+)
+
+// Table columns: where the description starts and where each number ends
+const (
+	colDescription = margin
+	colQuantity    = 300
+	colUnitPrice   = 370
+	colDiscount    = 435
+	colTax         = 490
+	colTotal       = right
+)
+
+// helveticaWidths are the widths of printable ASCII characters in the
+// standard Helvetica font, in thousandths of the font size. Helvetica-Bold
+// differs for letters but not for the digits and symbols in amounts.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0 to 9
+	278, 278, 584, 584, 584, 556, 1015, // : to @
+	667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, // A to M
+	722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N to Z
+	278, 278, 278, 469, 556, 333, // [ to `
+	556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, // a to m
+	556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, // n to z
+	334, 260, 334, 584, // { to ~
+}
+
+// textWidth measures s in points when set in Helvetica at size
+func textWidth(s string, size float64) float64 {
+	var width int
+	for _, b := range winAnsi(s) {
+		if b >= 32 && b <= 126 {
+			width += helveticaWidths[b-32]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// winAnsi converts s to the single-byte encoding of the standard PDF fonts.
+// Latin-1 characters carry over; anything else becomes a question mark.
+func winAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 32 && r <= 126, r >= 160 && r <= 255:
+			out = append(out, byte(r))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// pdfString quotes s as a PDF literal string
+func pdfString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('(')
+	for _, b := range winAnsi(s) {
+		if b == '(' || b == ')' || b == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(b)
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+// fit shortens s with an ellipsis until it is no wider than width
+func fit(s string, size, width float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+// pdfWriter lays out text on pages and assembles them into a PDF file
+type pdfWriter struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+}
+
+func (w *pdfWriter) newPage() {
+	w.page = &bytes.Buffer{}
+	w.pages = append(w.pages, w.page)
+	w.y = pageHeight - margin
+}
+
+func (w *pdfWriter) text(x, y, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(w.page, "BT /%s %s Tf %s %s Td %s Tj ET\n", font, num(size), num(x), num(y), pdfString(s))
+}
+
+// textRight sets s so that it ends at x
+func (w *pdfWriter) textRight(x, y, size float64, bold bool, s string) {
+	w.text(x-textWidth(s, size), y, size, bold, s)
+}
+
+func (w *pdfWriter) rule(x1, x2, y float64) {
+	fmt.Fprintf(w.page, "0.5 w %s %s m %s %s l S\n", num(x1), num(y), num(x2), num(y))
+}
+
+// bytes writes out the document: catalog, page tree, fonts, then a page
+// and content stream per page, followed by the cross-reference table
+func (w *pdfWriter) bytes(title string) []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	const firstPage = 6
+	var kids bytes.Buffer
+	for i := range w.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(w.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title %s /Producer (shopping-cart) >>", pdfString(title)))
+	for i, content := range w.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// num prints a coordinate without needless decimals
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// PDF renders the invoice as an A4 PDF document, using only the fonts every
+// PDF reader has built in
+func PDF(inv models.Invoice) []byte {
+	w := &pdfWriter{}
+	w.newPage()
+
+	w.text(margin, w.y-20, 22, true, "INVOICE")
+	w.textRight(right, w.y-12, 12, true, inv.Number)
+	w.textRight(right, w.y-28, 10, false, "Issued "+inv.IssuedAt.Format(dateLayout))
+	w.textRight(right, w.y-42, 10, false, "Order "+inv.OrderNumber)
+	w.y -= 90
+
+	// Addresses side by side
+	top := w.y
+	for _, block := range []struct {
+		x       float64
+		heading string
+		address models.AddressSnapshot
+	}{{margin, "BILL TO", inv.BillingAddress}, {300, "SHIP TO", inv.ShippingAddress}} {
+		y := top
+		w.text(block.x, y, 9, true, block.heading)
+		for _, line := range addressLines(block.address) {
+			y -= 14
+			w.text(block.x, y, 10, false, fit(line, 10, 220))
+		}
+		w.y = min(w.y, y)
+	}
+	w.y -= 40
+
+	header := func() {
+		w.text(colDescription, w.y, 9, true, "DESCRIPTION")
+		w.textRight(colQuantity, w.y, 9, true, "QTY")
+		w.textRight(colUnitPrice, w.y, 9, true, "UNIT PRICE")
+		w.textRight(colDiscount, w.y, 9, true, "DISCOUNT")
+		w.textRight(colTax, w.y, 9, true, "TAX")
+		w.textRight(colTotal, w.y, 9, true, "TOTAL")
+		w.rule(margin, right, w.y-6)
+		w.y -= 22
+	}
+	header()
+	for _, line := range inv.Lines {
+		if w.y < margin+40 {
+			w.newPage()
+			header()
+		}
+		w.text(colDescription, w.y, 10, false, fit(line.Description, 10, colQuantity-colDescription-40))
+		w.textRight(colQuantity, w.y, 10, false, strconv.Itoa(line.Quantity))
+		w.textRight(colUnitPrice, w.y, 10, false, Money(line.UnitPrice))
+		w.textRight(colDiscount, w.y, 10, false, Money(line.Discount))
+		w.textRight(colTax, w.y, 10, false, Money(line.Tax))
+		w.textRight(colTotal, w.y, 10, false, Money(line.Total))
+		w.y -= 18
+	}
+	w.rule(margin, right, w.y+8)
+	w.y -= 10
+
+	rows := summary(inv)
+	if w.y < margin+40+float64(len(rows)+1)*16 {
+		w.newPage()
+	}
+	for _, row := range rows {
+		w.text(330, w.y, 10, false, fit(row.Description, 10, 140))
+		w.textRight(right, w.y, 10, false, Money(row.Amount))
+		w.y -= 16
+	}
+	w.rule(330, right, w.y+10)
+	w.y -= 4
+	w.text(330, w.y, 11, true, "Total")
+	w.textRight(right, w.y, 11, true, Money(inv.Total))
+
+	// Page numbers go on last, once the page count is known
+	for i, page := range w.pages {
+		w.page = page
+		w.textRight(right, margin-20, 8, false, fmt.Sprintf("%s - page %d of %d", inv.Number, i+1, len(w.pages)))
+	}
+
+	return w.bytes("Invoice " + inv.Number)
+}
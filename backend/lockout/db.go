@@ -0,0 +1,64 @@
+package lockout
+
+import (
+	"errors"
+	"time"
+
+	"shopping-cart/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStore keeps records in the database, so every node behind a load
+// balancer sees the same counts
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Get(key string) (Record, error) {
+	var attempt models.LoginAttempt
+	err := s.db.Where("key = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Record{}, nil
+	}
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{Failures: attempt.Failures, LastFailure: attempt.LastFailure, LockedUntil: attempt.LockedUntil}, nil
+}
+
+func (s *DBStore) Fail(key string, now time.Time, window time.Duration) (Record, error) {
+	var record Record
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Counting happens in one statement so racing nodes do not lose failures
+		attempt := models.LoginAttempt{Key: key, Failures: 1, LastFailure: now}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"failures":     gorm.Expr("CASE WHEN last_failure < ? THEN 1 ELSE failures + 1 END", now.Add(-window)),
+				"last_failure": now,
+			}),
+		}).Create(&attempt).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("key = ?", key).First(&attempt).Error; err != nil {
+			return err
+		}
+		record = Record{Failures: attempt.Failures, LastFailure: attempt.LastFailure, LockedUntil: attempt.LockedUntil}
+		return nil
+	})
+	return record, err
+}
+
+func (s *DBStore) Lock(key string, until time.Time) error {
+	return s.db.Model(&models.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (s *DBStore) Reset(key string) error {
+	return s.db.Where("key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
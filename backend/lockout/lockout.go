@@ -0,0 +1,156 @@
+// Package lockout slows down and then locks out repeated failed logins.
+// Failures are counted per key, such as an account or an IP address, in a
+// Store that can be kept in memory on a single node or shared between nodes.
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Record is what a Store keeps for a key
+type Record struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Store keeps failure counts. Implementations must be safe for concurrent
+// use, and Fail must count every call even when calls race.
+type Store interface {
+	// Get returns the record for key, or a zero Record if there is none
+	Get(key string) (Record, error)
+	// Fail counts a failure at now and returns the updated record. Failures
+	// older than window are forgotten first.
+	Fail(key string, now time.Time, window time.Duration) (Record, error)
+	// Lock refuses key until the given time
+	Lock(key string, until time.Time) error
+	// Reset forgets key
+	Reset(key string) error
+}
+
+// Default is the store used by limiters that do not name their own. It
+// works for a single node until a shared store is configured.
+var Default Store = NewMemoryStore()
+
+// Limiter asks a caller to wait after each failure, twice as long every
+// time, and locks the key once Threshold failures are reached within Window.
+// A zero Threshold never locks.
+type Limiter struct {
+	Store        Store
+	Threshold    int
+	Window       time.Duration
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockDuration time.Duration
+}
+
+func (l *Limiter) store() Store {
+	if l.Store != nil {
+		return l.Store
+	}
+	return Default
+}
+
+// Delay is the wait after the given number of consecutive failures
+func (l *Limiter) Delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	delay := l.BaseDelay
+	for i := 1; i < failures && delay < l.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, l.MaxDelay)
+}
+
+// Check returns how long key must wait before another attempt, and whether
+// that is because it is locked
+func (l *Limiter) Check(key string, now time.Time) (time.Duration, bool, error) {
+	record, err := l.store().Get(key)
+	if err != nil {
+		return 0, false, err
+	}
+	if now.Before(record.LockedUntil) {
+		return record.LockedUntil.Sub(now), true, nil
+	}
+	if record.Failures == 0 || now.Sub(record.LastFailure) > l.Window {
+		return 0, false, nil
+	}
+	if wait := record.LastFailure.Add(l.Delay(record.Failures)).Sub(now); wait > 0 {
+		return wait, false, nil
+	}
+	return 0, false, nil
+}
+
+// Fail counts a failure for key and reports whether it locked the key
+func (l *Limiter) Fail(key string, now time.Time) (bool, error) {
+	record, err := l.store().Fail(key, now, l.Window)
+	if err != nil {
+		return false, err
+	}
+	if l.Threshold == 0 || record.Failures < l.Threshold || now.Before(record.LockedUntil) {
+		return false, nil
+	}
+	return true, l.store().Lock(key, now.Add(l.LockDuration))
+}
+
+// Reset clears the failures and any lock on key
+func (l *Limiter) Reset(key string) error {
+	return l.store().Reset(key)
+}
+
+// MemoryStore keeps records in this process
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[string]Record{}}
+}
+
+func (s *MemoryStore) Get(key string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.records[key], nil
+}
+
+func (s *MemoryStore) Fail(key string, now time.Time, window time.Duration) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop stale records now and then so the map does not grow forever
+	if len(s.records) > 10000 {
+		for k, r := range s.records {
+			if now.Sub(r.LastFailure) > window && now.After(r.LockedUntil) {
+				delete(s.records, k)
+			}
+		}
+	}
+
+	record := s.records[key]
+	if now.Sub(record.LastFailure) > window {
+		record.Failures = 0
+	}
+	record.Failures++
+	record.LastFailure = now
+	s.records[key] = record
+	return record, nil
+}
+
+func (s *MemoryStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[key]
+	record.LockedUntil = until
+	s.records[key] = record
+	return nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
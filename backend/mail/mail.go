@@ -0,0 +1,18 @@
+// Package mail sends email to customers through a pluggable transport.
+package mail
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is a mail transport
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the transport used to send mail. It logs messages until a
+// real transport is configured.
+var Default Mailer = NewLogMailer(nil)
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"log"
+	"time"
+
 	"shopping-cart/config"
+	"shopping-cart/lockout"
+	"shopping-cart/mail"
 	"shopping-cart/models"
+	"shopping-cart/passwordpolicy"
 	"shopping-cart/routes"
 
 	"github.com/gin-contrib/cors"
@@ -13,6 +19,9 @@ import (
 func main() {
 
 	config.Connect()
+	setupMail()
+	setupLoginAttempts()
+	setupPasswordPolicy()
 
 	config.DB.AutoMigrate(
 		&models.User{},
@@ -20,19 +29,56 @@ func main() {
 		&models.Cart{},
 		&models.CartItem{},
 		&models.Order{},
+		&models.OrderItem{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.Promotion{},
+		&models.OrderAdjustment{},
+		&models.TaxRate{},
+		&models.OrderTax{},
+		&models.Address{},
+		&models.ShippingMethod{},
+		&models.Payment{},
+		&models.Refund{},
+		&models.WebhookEvent{},
+		&models.ReturnRequest{},
+		&models.ReturnItem{},
+		&models.OrderEvent{},
+		&models.Invoice{},
+		&models.Shipment{},
+		&models.ShipmentItem{},
+		&models.IdempotencyKey{},
+		&models.Wishlist{},
+		&models.WishlistItem{},
+		&models.Review{},
+		&models.PasswordResetToken{},
+		&models.EmailVerificationToken{},
+		&models.TwoFactor{},
+		&models.RecoveryCode{},
+		&models.LoginChallenge{},
+		&models.LoginAttempt{},
+		&models.AuditLog{},
+		&models.ImpersonationSession{},
+		&models.ErasureRequest{},
+		&models.ServiceAccount{},
+		&models.APIKey{},
 	)
 
 	r := gin.Default()
+	if err := r.SetTrustedProxies(config.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// ✅ ADD THIS BLOCK
 	r.Use(cors.New(cors.Config{
 		AllowOrigins: []string{"https://abcde-ventures-nine.vercel.app"},
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders: []string{"Origin", "Content-Type", "Authorization"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "Idempotency-Key"},
 	}))
 
 	 // Seed sample data
 	 seedData()
+	 backfillOrderNumbers()
 	 routes.RegisterRoutes(r)
 	 r.Run(":8080")
 	}
@@ -50,8 +96,10 @@ func main() {
 	 userPassword, _ := bcrypt.GenerateFromPassword([]byte("user123"), bcrypt.DefaultCost)
 
 	 // Create admin and regular user
-	 admin := models.User{Username: "admin", Password: string(adminPassword), Admin: true}
-	 user := models.User{Username: "user", Password: string(userPassword), Admin: false}
+	 adminEmail, userEmail := "admin@example.com", "user@example.com"
+	 verifiedAt := time.Now()
+	 admin := models.User{Username: "admin", Email: &adminEmail, EmailVerifiedAt: &verifiedAt, Password: string(adminPassword), Admin: true}
+	 user := models.User{Username: "user", Email: &userEmail, EmailVerifiedAt: &verifiedAt, Password: string(userPassword), Admin: false}
 	 config.DB.Create(&admin)
 	 config.DB.Create(&user)
 
@@ -73,6 +121,12 @@ func main() {
 	 config.DB.Create(&item7)
 	 config.DB.Create(&item8)
 
+	 // Create shipping methods
+	 standard := models.ShippingMethod{Code: "standard", Name: "Standard", Type: models.ShippingFlat, BaseRate: 4.99, FreeOver: 100}
+	 express := models.ShippingMethod{Code: "express", Name: "Express", Type: models.ShippingWeight, BaseRate: 9.99, PerKg: 2}
+	 config.DB.Create(&standard)
+	 config.DB.Create(&express)
+
 	 // Create cart for user and add items
 	 cart := models.Cart{UserID: user.ID}
 	 config.DB.Create(&cart)
@@ -81,7 +135,69 @@ func main() {
 	 config.DB.Create(&cartItem1)
 	 config.DB.Create(&cartItem2)
 
+	 // Create default address for user
+	 address := models.Address{UserID: user.ID, Name: "Demo User", Line1: "1 Market St", City: "San Francisco", Region: "CA", PostalCode: "94105", Country: "US", IsDefault: true}
+	 config.DB.Create(&address)
+
 	 // Create order for user
-	 order := models.Order{CartID: cart.ID, UserID: user.ID, Total: 1998.97, Status: "completed"}
+	 order := models.Order{CartID: cart.ID, UserID: user.ID, Total: 1998.97, Status: "completed", ShippingAddress: address.Snapshot(), BillingAddress: address.Snapshot()}
 	 config.DB.Create(&order)
 	}
+
+	// backfillOrderNumbers numbers orders placed before order numbers existed,
+	// and copies the numbers onto returns opened before returns kept them.
+	// Order.Number is nullable so the unique index can be added to those rows first.
+	func backfillOrderNumbers() {
+	 var orders []models.Order
+	 config.DB.Where("number IS NULL").Find(&orders)
+	 for _, order := range orders {
+	  if err := order.AssignNumber(config.DB); err == nil {
+	   config.DB.Model(&order).Update("number", order.Number)
+	  }
+	 }
+	 config.DB.Exec("UPDATE return_requests SET order_number = (SELECT number FROM orders WHERE orders.id = return_requests.order_id) WHERE order_number IS NULL OR order_number = ''")
+	}
+
+	// setupMail picks the mail transport from the environment
+	func setupMail() {
+	 switch {
+	 case config.SMTPAddr != "":
+	  mail.Default = mail.NewSMTPMailer(config.SMTPAddr, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	 case config.MailFile != "":
+	  mailer, err := mail.NewFileMailer(config.MailFile)
+	  if err != nil {
+	   log.Fatalf("mail file: %v", err)
+	  }
+	  mail.Default = mailer
+	 }
+	}
+
+	// setupLoginAttempts shares failed login counts through the database
+	// when several nodes serve logins
+	func setupLoginAttempts() {
+	 if config.LoginAttemptStore == "db" {
+	  lockout.Default = lockout.NewDBStore(config.DB)
+	 }
+	}
+
+	// setupPasswordPolicy builds the policy for new passwords from the environment
+	func setupPasswordPolicy() {
+	 policy := &passwordpolicy.Policy{
+	  MinLength:      config.PasswordMinLength,
+	  MaxLength:      72,
+	  RequireUpper:   config.PasswordRequireUpper,
+	  RequireLower:   config.PasswordRequireLower,
+	  RequireDigit:   config.PasswordRequireDigit,
+	  RequireSpecial: config.PasswordRequireSpecial,
+	  Blocklist:      passwordpolicy.CommonPasswords(),
+	  RejectUsername: config.PasswordRejectUsername,
+	 }
+	 if config.PasswordBlocklistFile != "" {
+	  blocklist, err := passwordpolicy.LoadBlocklist(config.PasswordBlocklistFile)
+	  if err != nil {
+	   log.Fatalf("password blocklist: %v", err)
+	  }
+	  policy.Blocklist = blocklist
+	 }
+	 passwordpolicy.Default = policy
+	}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"strings"
+
 	"shopping-cart/config"
 	"shopping-cart/models"
 
@@ -11,11 +13,52 @@ func AuthMiddleware() gin.HandlerFunc {
  return func(c *gin.Context) {
   token := c.GetHeader("Authorization")
   var user models.User
+  // Users without a session have an empty token, so it must not match
+  if token == "" {
+   c.JSON(401, gin.H{"error": "Invalid token"})
+   c.Abort()
+   return
+  }
+  // Admins acting as a customer use an impersonation token instead
+  if strings.HasPrefix(token, ImpersonationPrefix) {
+   impersonated, ok := impersonate(c, token)
+   if !ok {
+    return
+   }
+   c.Set("user", impersonated)
+   c.Next()
+   return
+  }
+  // Service accounts use API keys, limited by the key's scopes
+  if strings.HasPrefix(token, APIKeyPrefix) {
+   service, ok := authenticateAPIKey(c, token)
+   if !ok {
+    return
+   }
+   c.Set("user", service)
+   c.Next()
+   return
+  }
   if err := config.DB.Where("token=?", token).First(&user).Error; err != nil {
    c.JSON(401, gin.H{"error": "Invalid token"})
    c.Abort()
    return
   }
+  if user.Disabled {
+   c.JSON(403, gin.H{"error": "Account is disabled"})
+   c.Abort()
+   return
+  }
+  // Admins must set up two-factor authentication first when it is required
+  if user.Admin && config.RequireAdmin2FA && !strings.HasPrefix(c.FullPath(), "/users/2fa") {
+   var enabled int64
+   config.DB.Model(&models.TwoFactor{}).Where("user_id = ? AND enabled = ?", user.ID, true).Count(&enabled)
+   if enabled == 0 {
+    c.JSON(403, gin.H{"error": "Two-factor authentication is required for admin accounts"})
+    c.Abort()
+    return
+   }
+  }
   c.Set("user", user)
   c.Next()
  }
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyHeader carries a client-chosen key that identifies one logical request
+const IdempotencyHeader = "Idempotency-Key"
+
+// recordingWriter keeps a copy of the response body as it is written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// fingerprint identifies a request by its method, path and body so that a
+// key reused for a different request can be told apart from a retry
+func fingerprint(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	io.WriteString(sum, r.Method+" "+r.URL.Path+"\n")
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// IdempotencyMiddleware makes a handler safe to retry. The first request
+// with an Idempotency-Key runs normally and its response is stored; repeats
+// of it within config.IdempotencyWindow get the stored response back without
+// running the handler again. A repeat that arrives while the first is still
+// running is rejected, as is a key reused for a different request. A key
+// whose request panicked is released, and one left behind by a stopped
+// server can be taken over once config.IdempotencyLease has passed.
+// Requests without the header are not affected. It must run after
+// AuthMiddleware.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			return
+		}
+		user := c.MustGet("user").(models.User)
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(config.IdempotencyMaxBody)+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		if len(body) > config.IdempotencyMaxBody {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body is too large"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		record := models.IdempotencyKey{
+			UserID:      user.ID,
+			Key:         key,
+			Fingerprint: fingerprint(c.Request, body),
+			LockedUntil: now.Add(config.IdempotencyLease),
+			ExpiresAt:   now.Add(config.IdempotencyWindow),
+		}
+
+		// Claim the key. The unique index makes this fail for a repeat,
+		// including one running at the same moment.
+		config.DB.Where("user_id = ? AND expires_at < ?", user.ID, now).Delete(&models.IdempotencyKey{})
+		if err := config.DB.Create(&record).Error; err != nil {
+			var existing models.IdempotencyKey
+			if err := config.DB.Where("user_id = ? AND key = ?", user.ID, key).First(&existing).Error; err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check Idempotency-Key"})
+				return
+			}
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+				return
+			case existing.Status == 0 && !takeOverKey(existing, record):
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+				return
+			case existing.Status != 0:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.Status, existing.ContentType, existing.Response)
+				c.Abort()
+				return
+			}
+			record.ID = existing.ID
+		}
+
+		// Let go of the key if the handler panics, so a retry can run
+		finished := false
+		defer func() {
+			if !finished {
+				config.DB.Delete(&record)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		finished = true
+
+		// Server errors are not kept, so the client can retry them for real
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			config.DB.Delete(&record)
+			return
+		}
+		config.DB.Model(&record).Updates(map[string]any{
+			"status":       status,
+			"content_type": writer.Header().Get("Content-Type"),
+			"response":     writer.body.Bytes(),
+		})
+	}
+}
+
+// takeOverKey claims an unfinished key whose lease has run out for record.
+// Only one of several retries arriving together gets it.
+func takeOverKey(existing, record models.IdempotencyKey) bool {
+	result := config.DB.Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status = 0 AND locked_until < ?", existing.ID, time.Now()).
+		Updates(map[string]any{"locked_until": record.LockedUntil, "expires_at": record.ExpiresAt})
+	return result.Error == nil && result.RowsAffected == 1
+}
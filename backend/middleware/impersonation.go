@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"shopping-cart/config"
+	"shopping-cart/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImpersonationPrefix starts every impersonation token, so they can be told
+// apart from session tokens
+const ImpersonationPrefix = "imp_"
+
+// impersonationAllowed reports whether an admin acting as a customer may
+// make the request. They can look at anything the customer can and work
+// with the cart and wishlist, but not check out or change the account.
+func impersonationAllowed(c *gin.Context) bool {
+	path := c.FullPath()
+	switch {
+	case c.Request.Method == http.MethodGet:
+		return true
+	case strings.HasPrefix(path, "/carts"), strings.HasPrefix(path, "/wishlist"):
+		return true
+	case path == "/impersonation":
+		return true
+	}
+	return false
+}
+
+// impersonate authenticates an impersonation token and returns the
+// customer it stands for. It responds itself and returns false when the
+// token or the request is refused. Every change made while impersonating
+// is written to the audit log.
+func impersonate(c *gin.Context, token string) (models.User, bool) {
+	var user models.User
+	sum := sha256.Sum256([]byte(token))
+
+	var session models.ImpersonationSession
+	if err := config.DB.Preload("Admin").
+		Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hex.EncodeToString(sum[:]), time.Now()).
+		First(&session).Error; err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return user, false
+	}
+	// The admin must still be an admin in good standing
+	if session.Admin == nil || !session.Admin.Admin || session.Admin.Disabled {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return user, false
+	}
+	if err := config.DB.First(&user, session.UserID).Error; err != nil || user.Disabled {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return user, false
+	}
+
+	if !impersonationAllowed(c) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a customer"})
+		return user, false
+	}
+	if c.Request.Method != http.MethodGet {
+		config.DB.Create(&models.AuditLog{
+			Actor:   session.Admin.Username,
+			Action:  "impersonation.request",
+			Subject: "user:" + strings.ToLower(user.Username),
+			Detail:  c.Request.Method + " " + c.Request.URL.Path,
+			IP:      c.ClientIP(),
+		})
+	}
+	c.Set("impersonation", session)
+	return user, true
+}
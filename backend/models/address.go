@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type Address struct {
+	ID         uint `gorm:"primaryKey"`
+	UserID     uint `gorm:"index"`
+	Name       string
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+	Phone      string
+	IsDefault  bool
+	CreatedAt  time.Time
+}
+
+// AddressSnapshot is a copy of an address kept on an order, so later edits
+// to the address book don't change where an order was sent
+type AddressSnapshot struct {
+	Name       string
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+	Phone      string
+}
+
+// Snapshot copies the address for storing on an order
+func (a Address) Snapshot() AddressSnapshot {
+	return AddressSnapshot{
+		Name:       a.Name,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+	}
+}
+
+// TaxRegion is the region code used to look up tax rates, e.g. "US-CA",
+// or just the country when the address has no region
+func (a AddressSnapshot) TaxRegion() string {
+	if a.Region == "" {
+		return strings.ToUpper(a.Country)
+	}
+	return strings.ToUpper(a.Country + "-" + a.Region)
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// ServiceAccount is a non-human client, such as the warehouse or ERP
+// integration, that calls the API with API keys. It acts through its own
+// user row, which cannot log in, so orders and audit entries name it.
+type ServiceAccount struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"uniqueIndex"`
+	Description string
+	UserID      uint `gorm:"uniqueIndex"`
+	CreatedBy   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	User        *User    `gorm:"foreignKey:UserID"`
+	Keys        []APIKey `gorm:"foreignKey:ServiceAccountID"`
+}
+
+// APIKey lets a service account call the endpoints its scopes allow. Only
+// a SHA-256 hash of the key is stored; Prefix is kept to tell keys apart.
+type APIKey struct {
+	ID               uint `gorm:"primaryKey"`
+	ServiceAccountID uint `gorm:"index"`
+	Name             string
+	Prefix           string
+	KeyHash          string `gorm:"uniqueIndex"`
+	Scopes           string // space-separated
+	ExpiresAt        *time.Time
+	RevokedAt        *time.Time
+	LastUsedAt       *time.Time
+	LastUsedIP       string
+	CreatedAt        time.Time
+	ServiceAccount   *ServiceAccount `gorm:"foreignKey:ServiceAccountID"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AuditLog records a security-relevant event. Actor is the username of
+// whoever caused it, or "system"; Subject is what it happened to, e.g.
+// "user:alice".
+type AuditLog struct {
+	ID        uint `gorm:"primaryKey"`
+	Actor     string
+	Action    string `gorm:"index"`
+	Subject   string `gorm:"index"`
+	Detail    string
+	IP        string
+	CreatedAt time.Time `gorm:"index"`
+}
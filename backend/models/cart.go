@@ -1,10 +1,12 @@
 package models
 
 type Cart struct {
-	ID     uint `gorm:"primaryKey"`
-	UserID uint
+	ID       uint `gorm:"primaryKey"`
+	UserID   uint
+	CouponID *uint
 
 	// Relationships
 	User      *User      `gorm:"foreignKey:UserID"`
+	Coupon    *Coupon    `gorm:"foreignKey:CouponID"`
 	CartItems []CartItem `gorm:"foreignKey:CartID"`
 }
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Coupon types
+const (
+	CouponPercent = "percent"
+	CouponFixed   = "fixed"
+)
+
+type Coupon struct {
+	ID           uint   `gorm:"primaryKey"`
+	Code         string `gorm:"uniqueIndex"`
+	Type         string
+	Value        float64
+	MinCartValue float64
+	ExpiresAt    *time.Time
+	UsageLimit   int // 0 means unlimited
+	PerUserLimit int // 0 means unlimited
+	UsedCount    int
+	ItemIDs      []uint   `gorm:"serializer:json"`
+	Categories   []string `gorm:"serializer:json"`
+	CreatedAt    time.Time
+}
+
+// CouponRedemption records a coupon being used on an order
+type CouponRedemption struct {
+	ID        uint `gorm:"primaryKey"`
+	CouponID  uint `gorm:"index"`
+	UserID    uint `gorm:"index"`
+	OrderID   uint
+	CreatedAt time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// EmailVerificationToken confirms that a user can read mail sent to Email.
+// Only a SHA-256 hash of the token is stored. A token stops working when it
+// expires or when the user's email changes.
+type EmailVerificationToken struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"index"`
+	Email     string
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Erasure request statuses
+const (
+	ErasurePending   = "pending"
+	ErasureCancelled = "cancelled"
+	ErasureCompleted = "completed"
+)
+
+// ErasureRequest is a customer's request to have their account erased. It
+// can be cancelled until ScheduledFor, after which an admin carries it out.
+type ErasureRequest struct {
+	ID           uint   `gorm:"primaryKey"`
+	UserID       uint   `gorm:"index"`
+	Status       string `gorm:"index"`
+	Reason       string
+	ScheduledFor time.Time
+	CancelledAt  *time.Time
+	CompletedAt  *time.Time
+	CompletedBy  string // username of the admin who carried it out
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	User         *User `gorm:"foreignKey:UserID"`
+}
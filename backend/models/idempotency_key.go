@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers a request made with an Idempotency-Key header and
+// the response it got, so a retry of the same request gets the same response
+// instead of running again. Status is 0 while the first request is running,
+// and LockedUntil is when another request may take over the key if the
+// first never finished.
+type IdempotencyKey struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"uniqueIndex:idx_idempotency_user_key"`
+	Key         string `gorm:"uniqueIndex:idx_idempotency_user_key"`
+	Fingerprint string // hash of the method, path and body
+	Status      int
+	ContentType string
+	Response    []byte
+	LockedUntil time.Time
+	CreatedAt   time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+}
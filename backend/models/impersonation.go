@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ImpersonationSession lets an admin act as a customer for a short time,
+// e.g. to reproduce a problem with their cart. Only a SHA-256 hash of the
+// token is stored.
+type ImpersonationSession struct {
+	ID        uint   `gorm:"primaryKey"`
+	AdminID   uint   `gorm:"index"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	Reason    string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+	Admin     *User `gorm:"foreignKey:AdminID"`
+}
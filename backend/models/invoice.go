@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvoiceIssued is returned when something tries to change an invoice
+var ErrInvoiceIssued = errors.New("invoices cannot be changed once issued")
+
+// Invoice is the bill for an order. It is a snapshot taken when the invoice
+// is issued, so later changes to the order or the customer's addresses do
+// not alter it. Seq numbers invoices one after another, separately from
+// order IDs.
+type Invoice struct {
+	ID          uint   `gorm:"primaryKey"`
+	Seq         uint   `gorm:"uniqueIndex"`
+	Number      string `gorm:"uniqueIndex"` // e.g. INV-000001
+	OrderID     uint   `gorm:"uniqueIndex" json:"-"`
+	OrderNumber string
+	Customer    string
+	IssuedAt    time.Time
+
+	BillingAddress  AddressSnapshot `gorm:"embedded;embeddedPrefix:bill_"`
+	ShippingAddress AddressSnapshot `gorm:"embedded;embeddedPrefix:ship_"`
+
+	Lines       []InvoiceLine   `gorm:"serializer:json"`
+	Adjustments []InvoiceCharge `gorm:"serializer:json"`
+	Taxes       []InvoiceCharge `gorm:"serializer:json"`
+
+	Subtotal       float64
+	Discount       float64
+	ShippingMethod string
+	Shipping       float64
+	Tax            float64
+	TaxMode        string
+	Total          float64
+}
+
+// InvoiceLine is one ordered item on an invoice
+type InvoiceLine struct {
+	Description string
+	Quantity    int
+	UnitPrice   float64
+	Discount    float64
+	Tax         float64
+	Total       float64
+}
+
+// InvoiceCharge is a named amount on an invoice, such as a discount or a tax
+type InvoiceCharge struct {
+	Description string
+	Amount      float64
+}
+
+// InvoiceNumber formats an invoice sequence number
+func InvoiceNumber(seq uint) string {
+	return fmt.Sprintf("INV-%06d", seq)
+}
+
+// BeforeUpdate - hook to keep issued invoices immutable
+func (inv *Invoice) BeforeUpdate(tx *gorm.DB) error {
+	return ErrInvoiceIssued
+}
+
+// BeforeDelete - hook to keep issued invoices immutable
+func (inv *Invoice) BeforeDelete(tx *gorm.DB) error {
+	return ErrInvoiceIssued
+}
@@ -3,4 +3,11 @@ type Item struct {
  ID uint `gorm:"primaryKey"`
  Name string
  Price float64
+ Category string
+ TaxClass string `gorm:"default:standard"`
+ Weight float64 // kg
+ Stock *int // nil means stock is not tracked
+ Archived bool // no longer sold, kept because past orders refer to it
+ RatingAverage float64 // of approved reviews
+ RatingCount int
 }
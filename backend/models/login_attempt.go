@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// LoginAttempt counts failed logins for a key such as "user:alice" or
+// "ip:203.0.113.7" when the counters are shared through the database
+type LoginAttempt struct {
+	ID          uint   `gorm:"primaryKey"`
+	Key         string `gorm:"uniqueIndex"`
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
@@ -2,15 +2,42 @@ package models
 
 import "time"
 
+// Order is a placed order. Its ID is sequential, so it is left out of JSON
+// and customers refer to orders by Number instead; the same goes for the
+// OrderID of everything that belongs to an order.
 type Order struct {
-	ID        uint `gorm:"primaryKey"`
-	CartID    uint
-	UserID    uint
-	Total     float64
-	Status    string `gorm:"default:"pending""`
-	CreatedAt time.Time
-	
+	ID         uint    `gorm:"primaryKey" json:"-"`
+	Number     *string `gorm:"uniqueIndex"` // e.g. ABC-2026-7F3K9
+	CartID     uint
+	UserID     uint
+	Subtotal   float64
+	Discount   float64
+	CouponCode string
+	Tax        float64
+	TaxMode    string
+	TaxRegion  string
+	Total      float64
+	Refunded   float64
+	Status     string `gorm:"default:pending"`
+	CreatedAt  time.Time
+
+	// StockReserved is set while the order holds item stock
+	StockReserved bool
+
+	// Where and how the order ships and who is billed, copied at checkout
+	ShippingMethod  string
+	ShippingCost    float64
+	ShippingAddress AddressSnapshot `gorm:"embedded;embeddedPrefix:ship_"`
+	BillingAddress  AddressSnapshot `gorm:"embedded;embeddedPrefix:bill_"`
+
 	// Relationships
-	Cart *Cart `gorm:"foreignKey:CartID"`
-	User *User `gorm:"foreignKey:UserID"`
+	Cart        *Cart             `gorm:"foreignKey:CartID"`
+	User        *User             `gorm:"foreignKey:UserID"`
+	Items       []OrderItem       `gorm:"foreignKey:OrderID"`
+	Adjustments []OrderAdjustment `gorm:"foreignKey:OrderID"`
+	Taxes       []OrderTax        `gorm:"foreignKey:OrderID"`
+	Payments    []Payment         `gorm:"foreignKey:OrderID"`
+	Returns     []ReturnRequest   `gorm:"foreignKey:OrderID"`
+	Events      []OrderEvent      `gorm:"foreignKey:OrderID"`
+	Shipments   []Shipment        `gorm:"foreignKey:OrderID"`
 }
@@ -0,0 +1,11 @@
+package models
+
+// OrderAdjustment records a discount that was applied to an order
+type OrderAdjustment struct {
+	ID          uint `gorm:"primaryKey"`
+	OrderID     uint `gorm:"index" json:"-"`
+	Source      string
+	Code        string
+	Description string
+	Amount      float64
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// OrderEvent is an entry in an order's audit trail: a status change, who
+// made it and why. Actor is a username, or "gateway" for payment events.
+type OrderEvent struct {
+	ID         uint `gorm:"primaryKey"`
+	OrderID    uint `gorm:"index" json:"-"`
+	Actor      string
+	FromStatus string
+	ToStatus   string
+	Note       string
+	CreatedAt  time.Time
+}
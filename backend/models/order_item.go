@@ -0,0 +1,21 @@
+package models
+
+// OrderItem is a snapshot of a cart line at checkout time
+type OrderItem struct {
+	ID       uint `gorm:"primaryKey"`
+	OrderID  uint `gorm:"index" json:"-"`
+	ItemID   uint
+	Name     string
+	Price    float64
+	Quantity int
+	Subtotal float64
+	Discount float64
+	Total    float64
+	TaxClass string
+	TaxRate  float64
+	Tax      float64
+
+	// Units that have left in shipments, and units sent back through returns
+	ShippedQuantity  int
+	ReturnedQuantity int
+}
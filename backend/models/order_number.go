@@ -0,0 +1,61 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OrderNumberPrefix starts every order number
+const OrderNumberPrefix = "ABC"
+
+// orderNumberAlphabet leaves out I, L, O and U so numbers read back over the
+// phone without confusion
+const orderNumberAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewOrderNumber returns a random order number for an order placed at t, such
+// as ABC-2026-7F3K9. Unlike the ID it says nothing about sales volume.
+func NewOrderNumber(t time.Time) (string, error) {
+	code := make([]byte, 5)
+	if _, err := rand.Read(code); err != nil {
+		return "", err
+	}
+	for i, b := range code {
+		code[i] = orderNumberAlphabet[int(b)%len(orderNumberAlphabet)]
+	}
+	return fmt.Sprintf("%s-%d-%s", OrderNumberPrefix, t.Year(), code), nil
+}
+
+// AssignNumber gives the order a number that no other order has
+func (o *Order) AssignNumber(tx *gorm.DB) error {
+	placed := o.CreatedAt
+	if placed.IsZero() {
+		placed = time.Now()
+	}
+	for range 5 {
+		number, err := NewOrderNumber(placed)
+		if err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&Order{}).Where("number = ?", number).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			o.Number = &number
+			return nil
+		}
+	}
+	return errors.New("no free order number found")
+}
+
+// BeforeCreate - hook to number new orders
+func (o *Order) BeforeCreate(tx *gorm.DB) error {
+	if o.Number != nil {
+		return nil
+	}
+	return o.AssignNumber(tx)
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PasswordResetToken lets a user set a new password without the old one.
+// Only a SHA-256 hash of the token is stored; the token itself is mailed to
+// the user. A token works once, until ExpiresAt.
+type PasswordResetToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Payment is a payment taken for an order through a payment provider
+type Payment struct {
+	ID             uint `gorm:"primaryKey"`
+	OrderID        uint `gorm:"index" json:"-"`
+	Provider       string
+	Reference      string `gorm:"index"`
+	Amount         float64
+	CapturedAmount float64
+	RefundedAmount float64
+	Status         string
+	Message        string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
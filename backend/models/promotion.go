@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Promotion types
+const (
+	PromotionBuyXGetY = "buy_x_get_y"
+	PromotionBundle   = "bundle"
+	PromotionTiered   = "tiered"
+)
+
+// PromotionTier is a quantity threshold for tiered pricing
+type PromotionTier struct {
+	MinQuantity int
+	PercentOff  float64
+}
+
+// Promotion is an automatic discount rule that needs no code.
+// Higher priority promotions are evaluated first; each cart unit can only
+// be used by one promotion, and an exclusive promotion stops evaluation.
+type Promotion struct {
+	ID          uint `gorm:"primaryKey"`
+	Name        string
+	Type        string
+	Priority    int
+	Exclusive   bool
+	Disabled    bool
+	ItemIDs     []uint `gorm:"serializer:json"`
+	BuyQuantity int
+	GetQuantity int
+	BundlePrice float64
+	Tiers       []PromotionTier `gorm:"serializer:json"`
+	StartsAt    *time.Time
+	EndsAt      *time.Time
+	CreatedAt   time.Time
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Refund statuses
+const (
+	RefundPending   = "pending" // sent to the gateway, outcome not recorded yet
+	RefundSucceeded = "succeeded"
+	RefundFailed    = "failed"
+)
+
+// Refund is money sent back against a payment. It is written before the
+// gateway is asked, so a refund that went through but could not be
+// recorded is still there as pending, and the gateway's refund webhook
+// settles it.
+type Refund struct {
+	ID        uint `gorm:"primaryKey"`
+	PaymentID uint `gorm:"index"`
+	OrderID   uint `gorm:"index"`
+	Amount    float64
+	Status    string `gorm:"index"`
+	Message   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
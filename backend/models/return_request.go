@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Return request statuses, in the order a return moves through them
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+	ReturnReceived  = "received"
+	ReturnRefunded  = "refunded"
+)
+
+// ReturnRequest is a customer's request to send back order lines (an RMA)
+type ReturnRequest struct {
+	ID           uint `gorm:"primaryKey"`
+	OrderID      uint `gorm:"index" json:"-"`
+	UserID       uint `gorm:"index"`
+	OrderNumber  string
+	Status       string
+	Reason       string
+	AdminNote    string
+	RefundAmount float64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	// Relationships
+	Items []ReturnItem `gorm:"foreignKey:ReturnRequestID"`
+}
+
+// ReturnItem is a quantity of one order line being returned
+type ReturnItem struct {
+	ID              uint `gorm:"primaryKey"`
+	ReturnRequestID uint `gorm:"index"`
+	OrderItemID     uint
+	Quantity        int
+	RefundAmount    float64
+}
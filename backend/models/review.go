@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Review moderation statuses
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Review is a customer's rating of an item. Only approved reviews are shown
+// and counted in the item's rating. Author is the reviewer's username when
+// the review was written.
+type Review struct {
+	ID               uint `gorm:"primaryKey"`
+	ItemID           uint `gorm:"uniqueIndex:idx_review_item_user"`
+	UserID           uint `gorm:"uniqueIndex:idx_review_item_user"`
+	Author           string
+	Rating           int // 1 to 5
+	Title            string
+	Body             string
+	VerifiedPurchase bool
+	Status           string `gorm:"index"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
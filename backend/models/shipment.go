@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Shipment is one parcel sent for an order. An order can ship in several
+// parcels, each carrying some of its lines.
+type Shipment struct {
+	ID           uint `gorm:"primaryKey"`
+	OrderID      uint `gorm:"index" json:"-"`
+	Carrier      string
+	TrackingCode string
+	CreatedAt    time.Time
+
+	Items []ShipmentItem `gorm:"foreignKey:ShipmentID"`
+}
+
+// ShipmentItem is how many units of an order line went out in a shipment
+type ShipmentItem struct {
+	ID          uint `gorm:"primaryKey"`
+	ShipmentID  uint `gorm:"index"`
+	OrderItemID uint
+	Quantity    int
+}
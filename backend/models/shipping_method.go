@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Shipping rate types
+const (
+	ShippingFlat   = "flat"
+	ShippingWeight = "weight"
+)
+
+// ShippingMethod is a way an order can be delivered. Flat methods cost
+// BaseRate, weight methods add PerKg for every kilogram, and any method
+// becomes free once the discounted cart value reaches FreeOver.
+type ShippingMethod struct {
+	ID        uint   `gorm:"primaryKey"`
+	Code      string `gorm:"uniqueIndex"`
+	Name      string
+	Type      string
+	BaseRate  float64
+	PerKg     float64
+	FreeOver  float64  // 0 means never free
+	Countries []string `gorm:"serializer:json"` // empty means everywhere
+	Disabled  bool
+	CreatedAt time.Time
+}
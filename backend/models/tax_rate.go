@@ -0,0 +1,28 @@
+package models
+
+// Tax classes
+const (
+	TaxClassStandard = "standard"
+)
+
+// TaxRate is one tax that applies to a tax class in a region.
+// Several rates for the same region and class (e.g. state and city) add up.
+type TaxRate struct {
+	ID       uint   `gorm:"primaryKey"`
+	Region   string `gorm:"index"`
+	TaxClass string
+	Name     string
+	Rate     float64 // percent
+}
+
+// OrderTax is the persisted tax summary of an order, kept so invoices stay
+// correct after rates change
+type OrderTax struct {
+	ID       uint `gorm:"primaryKey"`
+	OrderID  uint `gorm:"index" json:"-"`
+	Name     string
+	TaxClass string
+	Rate     float64
+	Taxable  float64
+	Amount   float64
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// TwoFactor holds a user's TOTP secret. It is kept apart from User so the
+// secret never ends up in a user listing. Enabled is false until the user
+// confirms enrollment with a first code. LastStep is the time step of the
+// last code accepted, so a code cannot be used twice.
+type TwoFactor struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"uniqueIndex"`
+	Secret    string
+	Enabled   bool
+	LastStep  int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RecoveryCode stands in for a TOTP code once, when the authenticator is
+// lost. Only a SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID       uint   `gorm:"primaryKey"`
+	UserID   uint   `gorm:"index"`
+	CodeHash string `gorm:"index"`
+	UsedAt   *time.Time
+}
+
+// LoginChallenge is the second step of a login for a user with two-factor
+// authentication: the password was right and a code is still needed. Only
+// a SHA-256 hash of the challenge token is stored.
+type LoginChallenge struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
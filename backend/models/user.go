@@ -1,10 +1,24 @@
 package models
 
+import "time"
+
 type User struct {
-	ID       uint   `gorm:"primaryKey"`
-	Username string `gorm:"unique"`
-	Password string
-	Token    string
-	CartID   uint
-	Admin    bool `gorm:"default:false"`
+	ID              uint   `gorm:"primaryKey"`
+	Username        string `gorm:"unique"`
+	DisplayName     string
+	Email           *string    `gorm:"uniqueIndex"`
+	EmailVerifiedAt *time.Time // set when the user follows the link mailed to Email
+	Password        string
+	Token           string
+	CartID          uint
+	Admin           bool            `gorm:"default:false"`
+	Preferences     UserPreferences `gorm:"embedded;embeddedPrefix:pref_"`
+	Disabled        bool            // disabled accounts cannot log in
+	AnonymizedAt    *time.Time      // set when the account is deleted; the row stays for its orders
+}
+
+// UserPreferences are settings users choose for themselves
+type UserPreferences struct {
+	Newsletter  bool
+	OrderEmails bool `gorm:"default:true"` // updates about their orders
 }
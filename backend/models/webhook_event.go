@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// WebhookEvent is a payment gateway event that has been received.
+// EventID is unique so redelivered events are only applied once.
+type WebhookEvent struct {
+	ID         uint   `gorm:"primaryKey"`
+	EventID    string `gorm:"uniqueIndex"`
+	Type       string
+	Reference  string `gorm:"index"`
+	Payload    string
+	Result     string // "applied" or "ignored"
+	ReceivedAt time.Time
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Wishlist is where a user keeps items they are not buying yet. When
+// ShareToken is set, anyone with the link can view the list read-only.
+type Wishlist struct {
+	ID         uint    `gorm:"primaryKey"`
+	UserID     uint    `gorm:"uniqueIndex"`
+	ShareToken *string `gorm:"uniqueIndex"`
+
+	// Relationships
+	User  *User          `gorm:"foreignKey:UserID"`
+	Items []WishlistItem `gorm:"foreignKey:WishlistID"`
+}
+
+// WishlistItem is an item on a wishlist. Quantity is kept when a cart line
+// is saved for later, so moving it back restores the line as it was.
+type WishlistItem struct {
+	ID         uint `gorm:"primaryKey"`
+	WishlistID uint `gorm:"uniqueIndex:idx_wishlist_item"`
+	ItemID     uint `gorm:"uniqueIndex:idx_wishlist_item"`
+	Quantity   int  `gorm:"default:1"`
+	CreatedAt  time.Time
+
+	// Relationships
+	Item *Item `gorm:"foreignKey:ItemID"`
+}
@@ -0,0 +1,95 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed common-passwords.txt
+var commonPasswords string
+
+// Blocklist is a set of passwords that must not be used. Entries are either
+// plain passwords, matched ignoring case, or SHA-1 hashes in hex as
+// published in breach corpora, optionally followed by ":count".
+type Blocklist struct {
+	plain  map[string]struct{}
+	hashes map[string]struct{}
+}
+
+// CommonPasswords returns the built-in list of very common passwords
+func CommonPasswords() *Blocklist {
+	b := &Blocklist{plain: map[string]struct{}{}, hashes: map[string]struct{}{}}
+	b.Read(strings.NewReader(commonPasswords))
+	return b
+}
+
+// LoadBlocklist adds the entries of the file at path to the built-in list
+func LoadBlocklist(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := CommonPasswords()
+	if err := b.Read(f); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Read adds one entry per line from r. Blank lines and lines starting with
+// # are skipped.
+func (b *Blocklist) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if hash, _, _ := strings.Cut(line, ":"); isSHA1(hash) {
+			b.hashes[strings.ToUpper(hash)] = struct{}{}
+			continue
+		}
+		b.plain[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Len is the number of entries
+func (b *Blocklist) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.plain) + len(b.hashes)
+}
+
+// Contains reports whether password is on the list
+func (b *Blocklist) Contains(password string) bool {
+	if b == nil {
+		return false
+	}
+	if _, ok := b.plain[strings.ToLower(password)]; ok {
+		return true
+	}
+	if len(b.hashes) > 0 {
+		_, ok := b.hashes[sha1Hex(password)]
+		return ok
+	}
+	return false
+}
+
+func isSHA1(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+// Package passwordpolicy decides whether a new password is good enough.
+// Every rule is checked, so a user sees all the problems at once.
+package passwordpolicy
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rule names used in failures
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "uppercase"
+	RuleLower     = "lowercase"
+	RuleDigit     = "digit"
+	RuleSpecial   = "special"
+	RuleBlocked   = "common"
+	RuleUsername  = "username"
+)
+
+// Failure is a rule a password broke
+type Failure struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Policy lists what a password needs. MaxLength is in bytes, since bcrypt
+// ignores everything after the first 72.
+type Policy struct {
+	MinLength      int
+	MaxLength      int
+	RequireUpper   bool
+	RequireLower   bool
+	RequireDigit   bool
+	RequireSpecial bool
+	// Blocklist holds common and breached passwords, see LoadBlocklist
+	Blocklist *Blocklist
+	// RejectUsername refuses passwords that contain the username or are
+	// a few edits away from it
+	RejectUsername bool
+}
+
+// Default is the policy for new passwords
+var Default = &Policy{
+	MinLength:      8,
+	MaxLength:      72,
+	RequireUpper:   true,
+	RequireLower:   true,
+	RequireDigit:   true,
+	RequireSpecial: true,
+	Blocklist:      CommonPasswords(),
+	RejectUsername: true,
+}
+
+// Check returns every rule password breaks for the given username, or nil
+// when it is acceptable
+func (p *Policy) Check(password, username string) []Failure {
+	var failures []Failure
+	fail := func(rule, format string, args ...any) {
+		failures = append(failures, Failure{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		fail(RuleMinLength, "Password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		fail(RuleMaxLength, "Password must be at most %d bytes", p.MaxLength)
+	}
+
+	var upper, lower, digit, special bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			special = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		fail(RuleUpper, "Password must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		fail(RuleLower, "Password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		fail(RuleDigit, "Password must contain a number")
+	}
+	if p.RequireSpecial && !special {
+		fail(RuleSpecial, "Password must contain a special character")
+	}
+
+	if p.Blocklist.Contains(password) {
+		fail(RuleBlocked, "Password is too common or has appeared in a data breach")
+	}
+	if p.RejectUsername && similar(password, username) {
+		fail(RuleUsername, "Password must not be similar to the username")
+	}
+	return failures
+}
+
+// similar reports whether password contains the username, is contained in
+// it, or is at most two edits away from it, ignoring case
+func similar(password, username string) bool {
+	password, username = strings.ToLower(password), strings.ToLower(username)
+	if len(username) < 3 {
+		return false
+	}
+	if strings.Contains(password, username) || strings.Contains(username, password) {
+		return true
+	}
+	return distance(password, username) <= 2
+}
+
+// distance is the Levenshtein distance between a and b
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// sha1Hex is the uppercase SHA-1 of s, the form breach corpora use
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
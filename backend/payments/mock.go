@@ -0,0 +1,149 @@
+package payments
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Tokens the mock gateway understands
+const (
+	MockTokenDecline = "tok_decline"
+	MockTokenPending = "tok_pending"
+)
+
+// Outcome is a scripted response for the mock gateway. If Err is set the
+// call fails with it, otherwise the call returns Status and Message.
+type Outcome struct {
+	Status  string
+	Message string
+	Err     error
+}
+
+type mockPayment struct {
+	authorized float64
+	captured   float64
+	refunded   float64
+	status     string
+}
+
+// MockGateway is an in-process gateway for development and tests.
+// It approves everything except the MockTokenDecline and MockTokenPending
+// tokens, unless outcomes have been scripted with Script.
+type MockGateway struct {
+	mu       sync.Mutex
+	script   []Outcome
+	payments map[string]*mockPayment
+	seq      int
+}
+
+func NewMockGateway() *MockGateway {
+	return &MockGateway{payments: map[string]*mockPayment{}}
+}
+
+// Script queues outcomes that the next calls return, in order
+func (g *MockGateway) Script(outcomes ...Outcome) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.script = append(g.script, outcomes...)
+}
+
+// next pops the next scripted outcome, if there is one
+func (g *MockGateway) next() (Outcome, bool) {
+	if len(g.script) == 0 {
+		return Outcome{}, false
+	}
+	outcome := g.script[0]
+	g.script = g.script[1:]
+	return outcome, true
+}
+
+func (g *MockGateway) Name() string {
+	return "mock"
+}
+
+func (g *MockGateway) Authorize(req AuthorizeRequest) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.seq++
+	result := Result{Reference: fmt.Sprintf("mock_%d_%d", req.OrderID, g.seq), Status: StatusAuthorized}
+	switch req.Token {
+	case MockTokenDecline:
+		result.Status, result.Message = StatusFailed, "Card declined"
+	case MockTokenPending:
+		result.Status = StatusPending
+	}
+	if outcome, ok := g.next(); ok {
+		if outcome.Err != nil {
+			return Result{}, outcome.Err
+		}
+		result.Status, result.Message = outcome.Status, outcome.Message
+	}
+
+	if result.Status != StatusFailed {
+		g.payments[result.Reference] = &mockPayment{authorized: req.Amount, status: result.Status}
+	}
+	return result, nil
+}
+
+func (g *MockGateway) Capture(reference string, amount float64) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.payments[reference]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+	if outcome, ok := g.next(); ok {
+		return Result{Reference: reference, Status: outcome.Status, Message: outcome.Message}, outcome.Err
+	}
+	if p.status != StatusAuthorized {
+		return Result{}, fmt.Errorf("payments: cannot capture a %s payment", p.status)
+	}
+	if amount > p.authorized {
+		return Result{}, fmt.Errorf("payments: capture of %.2f exceeds authorized %.2f", amount, p.authorized)
+	}
+	p.captured = amount
+	p.status = StatusCaptured
+	return Result{Reference: reference, Status: StatusCaptured}, nil
+}
+
+func (g *MockGateway) Void(reference string) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.payments[reference]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+	if outcome, ok := g.next(); ok {
+		return Result{Reference: reference, Status: outcome.Status, Message: outcome.Message}, outcome.Err
+	}
+	if p.status != StatusAuthorized && p.status != StatusPending {
+		return Result{}, fmt.Errorf("payments: cannot void a %s payment", p.status)
+	}
+	p.status = StatusVoided
+	return Result{Reference: reference, Status: StatusVoided}, nil
+}
+
+func (g *MockGateway) Refund(reference string, amount float64) (Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.payments[reference]
+	if !ok {
+		return Result{}, ErrUnknownPayment
+	}
+	if outcome, ok := g.next(); ok {
+		return Result{Reference: reference, Status: outcome.Status, Message: outcome.Message}, outcome.Err
+	}
+	if p.status != StatusCaptured && p.status != StatusRefunded {
+		return Result{}, fmt.Errorf("payments: cannot refund a %s payment", p.status)
+	}
+	if p.refunded+amount > p.captured+0.005 {
+		return Result{}, fmt.Errorf("payments: refund of %.2f exceeds remaining %.2f", amount, p.captured-p.refunded)
+	}
+	p.refunded += amount
+	p.status = StatusRefunded
+	return Result{Reference: reference, Status: StatusRefunded}, nil
+}
@@ -0,0 +1,45 @@
+// Package payments abstracts the payment gateway that takes money for orders.
+package payments
+
+import "errors"
+
+// Payment statuses
+const (
+	StatusPending    = "pending" // waiting for the gateway to confirm
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusFailed     = "failed"
+	StatusVoided     = "voided"
+	StatusRefunded   = "refunded"
+)
+
+// ErrUnknownPayment is returned for references the gateway does not know
+var ErrUnknownPayment = errors.New("payments: unknown payment reference")
+
+// AuthorizeRequest asks the gateway to hold funds for an order
+type AuthorizeRequest struct {
+	OrderID uint
+	Amount  float64
+	Token   string // card or wallet token from the client
+}
+
+// Result is the gateway's answer. A declined payment is a Result with
+// StatusFailed; an error means the gateway could not be reached or
+// rejected the call itself.
+type Result struct {
+	Reference string
+	Status    string
+	Message   string
+}
+
+// PaymentProvider is a payment gateway
+type PaymentProvider interface {
+	Name() string
+	Authorize(req AuthorizeRequest) (Result, error)
+	Capture(reference string, amount float64) (Result, error)
+	Void(reference string) (Result, error)
+	Refund(reference string, amount float64) (Result, error)
+}
+
+// Provider is the gateway used for checkout
+var Provider PaymentProvider = NewMockGateway()
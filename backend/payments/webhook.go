@@ -0,0 +1,107 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of a webhook body
+const SignatureHeader = "X-Payment-Signature"
+
+// Webhook event types
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventFailed     = "payment.failed"
+	EventVoided     = "payment.voided"
+	EventRefunded   = "payment.refunded"
+)
+
+// Event is a payment update sent by the gateway. For refunds, Amount is the
+// total refunded so far, so events can be applied in any order.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Reference string    `json:"reference"`
+	Amount    float64   `json:"amount"`
+	Message   string    `json:"message"`
+	Created   time.Time `json:"created"`
+}
+
+// Sign returns the signature header value for a webhook body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a signature header against the body in constant time
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if secret == "" || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// EventStatus maps an event type to the payment status it reports
+func EventStatus(eventType string) (string, bool) {
+	switch eventType {
+	case EventAuthorized:
+		return StatusAuthorized, true
+	case EventCaptured:
+		return StatusCaptured, true
+	case EventFailed:
+		return StatusFailed, true
+	case EventVoided:
+		return StatusVoided, true
+	case EventRefunded:
+		return StatusRefunded, true
+	}
+	return "", false
+}
+
+// transitions lists the statuses a payment can move to. Statuses only move
+// forward, so a late or replayed event cannot undo a newer one. Failed and
+// voided payments are final.
+var transitions = map[string][]string{
+	StatusPending:    {StatusAuthorized, StatusCaptured, StatusFailed, StatusVoided},
+	StatusAuthorized: {StatusCaptured, StatusVoided},
+	StatusCaptured:   {StatusRefunded},
+	StatusRefunded:   {StatusRefunded},
+}
+
+// CanTransition reports whether a payment may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReach reports whether a payment can get from one status to another,
+// directly or through statuses in between. An event whose status can be
+// reached but not moved to directly has arrived before the events it
+// follows, e.g. a refund before the capture.
+func CanReach(from, to string) bool {
+	seen := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		status := queue[0]
+		queue = queue[1:]
+		for _, next := range transitions[status] {
+			if next == to {
+				return true
+			}
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package pricing
+
+import (
+	"fmt"
+	"time"
+
+	"shopping-cart/models"
+)
+
+// couponApplies reports whether a coupon's item/category restrictions match the line
+func couponApplies(coupon models.Coupon, line Line) bool {
+	if len(coupon.ItemIDs) == 0 && len(coupon.Categories) == 0 {
+		return true
+	}
+	if containsID(coupon.ItemIDs, line.ItemID) {
+		return true
+	}
+	for _, category := range coupon.Categories {
+		if category != "" && category == line.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyCoupon applies the coupon to the eligible lines of the quote.
+// It returns an error message if the coupon cannot be used on this cart.
+// Usage limits live in the database and are checked by the caller.
+func (q *Quote) ApplyCoupon(coupon models.Coupon, now time.Time) string {
+	if coupon.ExpiresAt != nil && now.After(*coupon.ExpiresAt) {
+		return "Coupon has expired"
+	}
+	if q.Total < coupon.MinCartValue {
+		return fmt.Sprintf("Cart total must be at least %.2f to use this coupon", coupon.MinCartValue)
+	}
+
+	var eligible []int
+	var eligibleTotal float64
+	for i, line := range q.Lines {
+		if couponApplies(coupon, line) {
+			eligible = append(eligible, i)
+			eligibleTotal += line.Subtotal - line.Discount
+		}
+	}
+	if len(eligible) == 0 || eligibleTotal <= 0 {
+		return "Coupon does not apply to any items in your cart"
+	}
+
+	var amount float64
+	var description string
+	switch coupon.Type {
+	case models.CouponPercent:
+		amount = eligibleTotal * coupon.Value / 100
+		description = fmt.Sprintf("%g%% off", coupon.Value)
+	case models.CouponFixed:
+		amount = coupon.Value
+		description = fmt.Sprintf("%.2f off", coupon.Value)
+	default:
+		return "Coupon is not valid"
+	}
+	amount = Round(min(amount, eligibleTotal))
+
+	q.discountLines(eligible, amount)
+	q.Adjustments = append(q.Adjustments, Adjustment{
+		Source:      "coupon",
+		Code:        coupon.Code,
+		Description: description,
+		Amount:      amount,
+	})
+	return ""
+}
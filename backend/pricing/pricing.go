@@ -0,0 +1,145 @@
+// Package pricing turns cart items into priced lines and applies discounts
+// so that carts and orders always agree on the numbers.
+package pricing
+
+import (
+	"math"
+
+	"shopping-cart/models"
+)
+
+// Line is a priced cart line
+type Line struct {
+	CartItemID uint
+	ItemID     uint
+	Name       string
+	Category   string
+	TaxClass   string
+	UnitPrice  float64
+	Weight     float64 // kg per unit
+	Quantity   int
+	Subtotal   float64
+	Discount   float64
+	Total      float64 // after discounts, before any exclusive tax
+	TaxRate    float64
+	Tax        float64
+}
+
+// Adjustment explains a discount applied to the cart
+type Adjustment struct {
+	Source      string
+	Code        string
+	Description string
+	Amount      float64
+}
+
+// Quote is the priced view of a cart
+type Quote struct {
+	Lines          []Line
+	Adjustments    []Adjustment
+	Taxes          []TaxLine
+	TaxMode        string
+	TaxRegion      string
+	Subtotal       float64
+	Discount       float64
+	Tax            float64
+	ShippingMethod string
+	Shipping       float64
+	Total          float64
+}
+
+// Round rounds an amount to whole cents
+func Round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// NewQuote builds a quote from cart items. Items should have Item preloaded
+// so that names and categories are available to discount rules.
+func NewQuote(cartItems []models.CartItem) *Quote {
+	q := &Quote{}
+	for _, ci := range cartItems {
+		line := Line{
+			CartItemID: ci.ID,
+			ItemID:     ci.ItemID,
+			UnitPrice:  ci.Price,
+			Quantity:   ci.Quantity,
+			Subtotal:   Round(ci.Price * float64(ci.Quantity)),
+			TaxClass:   models.TaxClassStandard,
+		}
+		if ci.Item != nil {
+			line.Name = ci.Item.Name
+			line.Category = ci.Item.Category
+			line.Weight = ci.Item.Weight
+			if ci.Item.TaxClass != "" {
+				line.TaxClass = ci.Item.TaxClass
+			}
+		}
+		q.Lines = append(q.Lines, line)
+	}
+	q.recalculate()
+	return q
+}
+
+// spread splits amount across lines in proportion to weights, putting any
+// rounding remainder on the last weighted line
+func spread(weights []float64, amount float64) []float64 {
+	amounts := make([]float64, len(weights))
+	var base float64
+	last := -1
+	for i, w := range weights {
+		if w > 0 {
+			base += w
+			last = i
+		}
+	}
+	if base <= 0 || amount <= 0 {
+		return amounts
+	}
+	remaining := Round(amount)
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		share := Round(amount * w / base)
+		if i == last {
+			share = remaining
+		}
+		amounts[i] = share
+		remaining = Round(remaining - share)
+	}
+	return amounts
+}
+
+// discountLines spreads amount across the given lines in proportion to what
+// is still payable on each
+func (q *Quote) discountLines(indexes []int, amount float64) {
+	weights := make([]float64, len(q.Lines))
+	for _, i := range indexes {
+		weights[i] = q.Lines[i].Subtotal - q.Lines[i].Discount
+	}
+	for i, share := range spread(weights, amount) {
+		q.Lines[i].Discount = Round(q.Lines[i].Discount + share)
+	}
+	q.recalculate()
+}
+
+func (q *Quote) recalculate() {
+	q.Subtotal, q.Discount, q.Tax = 0, 0, 0
+	for i := range q.Lines {
+		line := &q.Lines[i]
+		line.Total = Round(line.Subtotal - line.Discount)
+		q.Subtotal += line.Subtotal
+		q.Discount += line.Discount
+		q.Tax += line.Tax
+	}
+	q.Subtotal = Round(q.Subtotal)
+	q.Discount = Round(q.Discount)
+	q.Tax = Round(q.Tax)
+	q.Total = Round(q.Subtotal - q.Discount)
+
+	// Inclusive tax is already part of the prices
+	if q.TaxMode != TaxInclusive {
+		q.Total = Round(q.Total + q.Tax)
+	}
+	q.Total = Round(q.Total + q.Shipping)
+}
@@ -0,0 +1,205 @@
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"shopping-cart/models"
+)
+
+// unit is a single cart unit that a promotion can claim
+type unit struct {
+	line  int
+	price float64
+}
+
+// promotionActive reports whether the promotion is enabled and inside its date window
+func promotionActive(promo models.Promotion, now time.Time) bool {
+	if promo.Disabled {
+		return false
+	}
+	if promo.StartsAt != nil && now.Before(*promo.StartsAt) {
+		return false
+	}
+	if promo.EndsAt != nil && now.After(*promo.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// sortPromotions orders promotions by priority (highest first), then by ID
+// so that evaluation is deterministic
+func sortPromotions(promotions []models.Promotion) []models.Promotion {
+	sorted := make([]models.Promotion, len(promotions))
+	copy(sorted, promotions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
+
+// ApplyPromotions evaluates the promotions against the quote. Each cart unit
+// can be claimed by at most one promotion, and once an exclusive promotion
+// applies no further promotions are evaluated. Every applied promotion adds
+// an adjustment explaining the discount.
+func (q *Quote) ApplyPromotions(promotions []models.Promotion, now time.Time) {
+	// Units of each line not yet claimed by a promotion
+	unclaimed := make([]int, len(q.Lines))
+	for i, line := range q.Lines {
+		unclaimed[i] = line.Quantity
+	}
+
+	for _, promo := range sortPromotions(promotions) {
+		if !promotionActive(promo, now) {
+			continue
+		}
+
+		var amounts []float64
+		var detail string
+		switch promo.Type {
+		case models.PromotionBuyXGetY:
+			amounts, detail = q.buyXGetY(promo, unclaimed)
+		case models.PromotionBundle:
+			amounts, detail = q.bundle(promo, unclaimed)
+		case models.PromotionTiered:
+			amounts, detail = q.tiered(promo, unclaimed)
+		}
+
+		var total float64
+		for i, amount := range amounts {
+			q.Lines[i].Discount = Round(q.Lines[i].Discount + amount)
+			total += amount
+		}
+		if total <= 0 {
+			continue
+		}
+		q.Adjustments = append(q.Adjustments, Adjustment{
+			Source:      "promotion",
+			Code:        promo.Name,
+			Description: detail,
+			Amount:      Round(total),
+		})
+		if promo.Exclusive {
+			break
+		}
+	}
+	q.recalculate()
+}
+
+// eligibleUnits returns the unclaimed units of lines matching itemIDs
+// (all lines when itemIDs is empty), most expensive first
+func (q *Quote) eligibleUnits(itemIDs []uint, unclaimed []int) []unit {
+	var units []unit
+	for i, line := range q.Lines {
+		if len(itemIDs) > 0 && !containsID(itemIDs, line.ItemID) {
+			continue
+		}
+		for n := 0; n < unclaimed[i]; n++ {
+			units = append(units, unit{line: i, price: line.UnitPrice})
+		}
+	}
+	sort.SliceStable(units, func(i, j int) bool {
+		return units[i].price > units[j].price
+	})
+	return units
+}
+
+// buyXGetY makes the cheapest GetQuantity units of every BuyQuantity+GetQuantity group free
+func (q *Quote) buyXGetY(promo models.Promotion, unclaimed []int) ([]float64, string) {
+	size := promo.BuyQuantity + promo.GetQuantity
+	if promo.BuyQuantity <= 0 || promo.GetQuantity <= 0 {
+		return nil, ""
+	}
+	units := q.eligibleUnits(promo.ItemIDs, unclaimed)
+	sets := len(units) / size
+	if sets == 0 {
+		return nil, ""
+	}
+
+	claimed := units[:sets*size]
+	freeUnits := claimed[len(claimed)-sets*promo.GetQuantity:]
+	amounts := make([]float64, len(q.Lines))
+	for _, u := range freeUnits {
+		amounts[u.line] += u.price
+	}
+	for _, u := range claimed {
+		unclaimed[u.line]--
+	}
+	return amounts, fmt.Sprintf("Buy %d get %d free: %d free", promo.BuyQuantity, promo.GetQuantity, len(freeUnits))
+}
+
+// bundle prices one unit of each listed item together at BundlePrice
+func (q *Quote) bundle(promo models.Promotion, unclaimed []int) ([]float64, string) {
+	if len(promo.ItemIDs) < 2 {
+		return nil, ""
+	}
+
+	// Find the line holding each bundle item and how many bundles fit
+	lines := make([]int, len(promo.ItemIDs))
+	bundles := -1
+	var regular float64
+	for n, id := range promo.ItemIDs {
+		lines[n] = -1
+		for i, line := range q.Lines {
+			if line.ItemID == id && unclaimed[i] > 0 {
+				lines[n] = i
+				break
+			}
+		}
+		if lines[n] < 0 {
+			return nil, ""
+		}
+		if bundles < 0 || unclaimed[lines[n]] < bundles {
+			bundles = unclaimed[lines[n]]
+		}
+		regular += q.Lines[lines[n]].UnitPrice
+	}
+	saving := regular - promo.BundlePrice
+	if bundles <= 0 || saving <= 0 {
+		return nil, ""
+	}
+
+	weights := make([]float64, len(q.Lines))
+	for _, i := range lines {
+		weights[i] += q.Lines[i].UnitPrice
+		unclaimed[i] -= bundles
+	}
+	return spread(weights, saving*float64(bundles)), fmt.Sprintf("%d bundle(s) at %.2f", bundles, promo.BundlePrice)
+}
+
+// tiered takes a percentage off all eligible units based on how many there are
+func (q *Quote) tiered(promo models.Promotion, unclaimed []int) ([]float64, string) {
+	units := q.eligibleUnits(promo.ItemIDs, unclaimed)
+
+	var best *models.PromotionTier
+	for i, tier := range promo.Tiers {
+		if tier.MinQuantity <= len(units) && tier.PercentOff > 0 && (best == nil || tier.MinQuantity > best.MinQuantity) {
+			best = &promo.Tiers[i]
+		}
+	}
+	if best == nil {
+		return nil, ""
+	}
+
+	weights := make([]float64, len(q.Lines))
+	var value float64
+	for _, u := range units {
+		weights[u.line] += u.price
+		value += u.price
+		unclaimed[u.line]--
+	}
+	return spread(weights, value*best.PercentOff/100), fmt.Sprintf("%g%% off %d or more", best.PercentOff, best.MinQuantity)
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
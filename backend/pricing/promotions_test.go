@@ -0,0 +1,259 @@
+package pricing
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"shopping-cart/models"
+)
+
+var promoNow = time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+
+// Items used by the promotion tests: 1 costs 10, 2 costs 20 and 3 costs 5
+var promoPrices = map[uint]float64{1: 10, 2: 20, 3: 5}
+
+// promoQuote builds a quote from item ID and quantity pairs
+func promoQuote(lines ...[2]int) *Quote {
+	var cartItems []models.CartItem
+	for i, line := range lines {
+		id := uint(line[0])
+		cartItems = append(cartItems, models.CartItem{ID: uint(i + 1), ItemID: id, Price: promoPrices[id], Quantity: line[1]})
+	}
+	return NewQuote(cartItems)
+}
+
+func promoTime(offset time.Duration) *time.Time {
+	t := promoNow.Add(offset)
+	return &t
+}
+
+func bogo(id uint, priority int, itemIDs ...uint) models.Promotion {
+	return models.Promotion{ID: id, Name: "bogo", Type: models.PromotionBuyXGetY, Priority: priority, ItemIDs: itemIDs, BuyQuantity: 1, GetQuantity: 1}
+}
+
+func bundleOf(id uint, priority int, price float64, itemIDs ...uint) models.Promotion {
+	return models.Promotion{ID: id, Name: "bundle", Type: models.PromotionBundle, Priority: priority, ItemIDs: itemIDs, BundlePrice: price}
+}
+
+func tier(id uint, priority int, name string, minQuantity int, percentOff float64, itemIDs ...uint) models.Promotion {
+	return models.Promotion{ID: id, Name: name, Type: models.PromotionTiered, Priority: priority, ItemIDs: itemIDs,
+		Tiers: []models.PromotionTier{{MinQuantity: minQuantity, PercentOff: percentOff}}}
+}
+
+func exclusive(promo models.Promotion) models.Promotion {
+	promo.Exclusive = true
+	return promo
+}
+
+func TestApplyPromotions(t *testing.T) {
+	tests := []struct {
+		name       string
+		quote      *Quote
+		promotions []models.Promotion
+		codes      []string  // applied promotions, in order
+		discounts  []float64 // discount on each line
+	}{
+		{
+			name:       "rules on different units stack",
+			quote:      promoQuote([2]int{1, 2}, [2]int{2, 2}),
+			promotions: []models.Promotion{bogo(1, 0, 1), tier(2, 0, "tier", 2, 10, 2)},
+			codes:      []string{"bogo", "tier"},
+			discounts:  []float64{10, 4},
+		},
+		{
+			name:       "higher priority claims the units first",
+			quote:      promoQuote([2]int{1, 2}),
+			promotions: []models.Promotion{tier(1, 1, "tier", 2, 50, 1), bogo(2, 2, 1)},
+			codes:      []string{"bogo"},
+			discounts:  []float64{10},
+		},
+		{
+			name:       "equal priority goes by lowest ID",
+			quote:      promoQuote([2]int{1, 2}),
+			promotions: []models.Promotion{tier(2, 0, "half off", 2, 50, 1), tier(1, 0, "tenth off", 2, 10, 1)},
+			codes:      []string{"tenth off"},
+			discounts:  []float64{2},
+		},
+		{
+			name:       "exclusive rule stops evaluation",
+			quote:      promoQuote([2]int{1, 2}, [2]int{2, 2}),
+			promotions: []models.Promotion{exclusive(bogo(1, 2, 1)), tier(2, 1, "tier", 2, 10, 2)},
+			codes:      []string{"bogo"},
+			discounts:  []float64{10, 0},
+		},
+		{
+			name:       "exclusive rule that does not apply lets others run",
+			quote:      promoQuote([2]int{2, 2}),
+			promotions: []models.Promotion{exclusive(bogo(1, 2, 3)), tier(2, 1, "tier", 2, 10, 2)},
+			codes:      []string{"tier"},
+			discounts:  []float64{4},
+		},
+		{
+			name:       "bundle uses what buy x get y left",
+			quote:      promoQuote([2]int{1, 3}, [2]int{2, 1}),
+			promotions: []models.Promotion{bogo(1, 2, 1), bundleOf(2, 1, 25, 1, 2)},
+			codes:      []string{"bogo", "bundle"},
+			discounts:  []float64{11.67, 3.33},
+		},
+		{
+			name:       "buy x get y finds no units after a bundle",
+			quote:      promoQuote([2]int{1, 1}, [2]int{2, 1}),
+			promotions: []models.Promotion{bundleOf(1, 2, 25, 1, 2), bogo(2, 1)},
+			codes:      []string{"bundle"},
+			discounts:  []float64{1.67, 3.33},
+		},
+		{
+			name:       "tiered counts only unclaimed units",
+			quote:      promoQuote([2]int{1, 2}, [2]int{2, 1}),
+			promotions: []models.Promotion{bundleOf(1, 2, 25, 1, 2), tier(2, 1, "tier", 2, 10)},
+			codes:      []string{"bundle"},
+			discounts:  []float64{1.67, 3.33},
+		},
+		{
+			name:       "tiered claims every eligible unit",
+			quote:      promoQuote([2]int{1, 4}),
+			promotions: []models.Promotion{tier(1, 2, "tier", 2, 20, 1), bogo(2, 1, 1)},
+			codes:      []string{"tier"},
+			discounts:  []float64{8},
+		},
+		{
+			name:  "rules outside their window are skipped",
+			quote: promoQuote([2]int{1, 2}, [2]int{2, 2}),
+			promotions: func() []models.Promotion {
+				ended := bogo(1, 0, 1)
+				ended.EndsAt = promoTime(-time.Hour)
+				upcoming := tier(2, 0, "tier", 2, 10, 2)
+				upcoming.StartsAt = promoTime(time.Hour)
+				disabled := bundleOf(3, 0, 25, 1, 2)
+				disabled.Disabled = true
+				return []models.Promotion{ended, upcoming, disabled}
+			}(),
+			codes:     nil,
+			discounts: []float64{0, 0},
+		},
+		{
+			name:  "rules inside their window apply",
+			quote: promoQuote([2]int{1, 2}),
+			promotions: func() []models.Promotion {
+				promo := bogo(1, 0, 1)
+				promo.StartsAt = promoTime(-time.Hour)
+				promo.EndsAt = promoTime(time.Hour)
+				return []models.Promotion{promo}
+			}(),
+			codes:     []string{"bogo"},
+			discounts: []float64{10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.quote.ApplyPromotions(tt.promotions, promoNow)
+
+			var codes []string
+			var total float64
+			for _, adjustment := range tt.quote.Adjustments {
+				codes = append(codes, adjustment.Code)
+				total += adjustment.Amount
+			}
+			if !slices.Equal(codes, tt.codes) {
+				t.Errorf("applied %v, want %v", codes, tt.codes)
+			}
+
+			var discounts []float64
+			var want float64
+			for i, line := range tt.quote.Lines {
+				discounts = append(discounts, line.Discount)
+				want += tt.discounts[i]
+			}
+			if !slices.Equal(discounts, tt.discounts) {
+				t.Errorf("line discounts %v, want %v", discounts, tt.discounts)
+			}
+			if tt.quote.Discount != Round(want) || Round(total) != Round(want) {
+				t.Errorf("discount %.2f and adjustments %.2f, want %.2f", tt.quote.Discount, total, want)
+			}
+		})
+	}
+}
+
+func TestPromotionClaims(t *testing.T) {
+	tests := []struct {
+		name      string
+		quote     *Quote
+		promo     models.Promotion
+		unclaimed []int
+		amounts   []float64
+		left      []int
+	}{
+		{
+			name:      "buy x get y frees the cheapest unit of each set",
+			quote:     promoQuote([2]int{1, 1}, [2]int{2, 1}, [2]int{3, 1}),
+			promo:     bogo(1, 0),
+			unclaimed: []int{1, 1, 1},
+			amounts:   []float64{10, 0, 0},
+			left:      []int{0, 0, 1},
+		},
+		{
+			name:      "buy x get y ignores units already claimed",
+			quote:     promoQuote([2]int{1, 3}),
+			promo:     bogo(1, 0, 1),
+			unclaimed: []int{1},
+			amounts:   nil,
+			left:      []int{1},
+		},
+		{
+			name:      "bundle claims one unit of each item per bundle",
+			quote:     promoQuote([2]int{1, 2}, [2]int{2, 3}),
+			promo:     bundleOf(1, 0, 25, 1, 2),
+			unclaimed: []int{2, 3},
+			amounts:   []float64{3.33, 6.67},
+			left:      []int{0, 1},
+		},
+		{
+			name:      "bundle needs every item unclaimed",
+			quote:     promoQuote([2]int{1, 1}, [2]int{2, 1}),
+			promo:     bundleOf(1, 0, 25, 1, 2),
+			unclaimed: []int{1, 0},
+			amounts:   nil,
+			left:      []int{1, 0},
+		},
+		{
+			name:  "tiered picks the highest tier reached",
+			quote: promoQuote([2]int{1, 3}),
+			promo: models.Promotion{ID: 1, Type: models.PromotionTiered, Tiers: []models.PromotionTier{
+				{MinQuantity: 2, PercentOff: 10}, {MinQuantity: 3, PercentOff: 20}, {MinQuantity: 5, PercentOff: 30},
+			}},
+			unclaimed: []int{3},
+			amounts:   []float64{6},
+			left:      []int{0},
+		},
+		{
+			name:      "tiered below its lowest tier claims nothing",
+			quote:     promoQuote([2]int{1, 3}),
+			promo:     tier(1, 0, "tier", 3, 10),
+			unclaimed: []int{2},
+			amounts:   nil,
+			left:      []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var amounts []float64
+			switch tt.promo.Type {
+			case models.PromotionBuyXGetY:
+				amounts, _ = tt.quote.buyXGetY(tt.promo, tt.unclaimed)
+			case models.PromotionBundle:
+				amounts, _ = tt.quote.bundle(tt.promo, tt.unclaimed)
+			case models.PromotionTiered:
+				amounts, _ = tt.quote.tiered(tt.promo, tt.unclaimed)
+			}
+			if !slices.Equal(amounts, tt.amounts) {
+				t.Errorf("amounts %v, want %v", amounts, tt.amounts)
+			}
+			if !slices.Equal(tt.unclaimed, tt.left) {
+				t.Errorf("unclaimed %v, want %v", tt.unclaimed, tt.left)
+			}
+		})
+	}
+}
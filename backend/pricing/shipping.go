@@ -0,0 +1,77 @@
+package pricing
+
+import (
+	"fmt"
+	"strings"
+
+	"shopping-cart/models"
+)
+
+// ShippingOption is what a shipping method would cost for a quote
+type ShippingOption struct {
+	MethodID uint
+	Code     string
+	Name     string
+	Cost     float64
+	Free     bool
+}
+
+// Weight is the total weight of the quote in kg
+func (q *Quote) Weight() float64 {
+	var weight float64
+	for _, line := range q.Lines {
+		weight += line.Weight * float64(line.Quantity)
+	}
+	return weight
+}
+
+// shipsTo reports whether the method delivers to the country
+func shipsTo(method models.ShippingMethod, country string) bool {
+	if len(method.Countries) == 0 {
+		return true
+	}
+	for _, c := range method.Countries {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShippingOptionFor prices a shipping method for the quote. It returns an
+// error message if the method cannot deliver to the country.
+func (q *Quote) ShippingOptionFor(method models.ShippingMethod, country string) (ShippingOption, string) {
+	option := ShippingOption{MethodID: method.ID, Code: method.Code, Name: method.Name}
+	if method.Disabled || !shipsTo(method, country) {
+		return option, "Shipping method is not available for this address"
+	}
+
+	switch method.Type {
+	case models.ShippingFlat:
+		option.Cost = method.BaseRate
+	case models.ShippingWeight:
+		option.Cost = method.BaseRate + method.PerKg*q.Weight()
+	default:
+		return option, fmt.Sprintf("Unknown shipping type %q", method.Type)
+	}
+
+	// Free shipping is judged on what the goods cost after discounts
+	if method.FreeOver > 0 && q.Subtotal-q.Discount >= method.FreeOver {
+		option.Cost = 0
+		option.Free = true
+	}
+	option.Cost = Round(option.Cost)
+	return option, ""
+}
+
+// ApplyShipping adds the cost of the shipping method to the quote
+func (q *Quote) ApplyShipping(method models.ShippingMethod, country string) string {
+	option, errMsg := q.ShippingOptionFor(method, country)
+	if errMsg != "" {
+		return errMsg
+	}
+	q.ShippingMethod = option.Name
+	q.Shipping = option.Cost
+	q.recalculate()
+	return ""
+}
@@ -0,0 +1,114 @@
+package pricing
+
+import (
+	"fmt"
+
+	"shopping-cart/models"
+)
+
+// Tax pricing modes
+const (
+	TaxExclusive = "exclusive" // prices exclude tax, tax is added on top
+	TaxInclusive = "inclusive" // prices include tax, tax is extracted from them
+)
+
+// TaxLine is one tax on a line, or a summary of one tax across the quote
+type TaxLine struct {
+	Name     string
+	TaxClass string
+	Rate     float64
+	Taxable  float64
+	Amount   float64
+}
+
+// TaxCalculator works out the taxes on a quote line for a region
+type TaxCalculator interface {
+	Calculate(region string, line Line, mode string) ([]TaxLine, error)
+}
+
+// RateTable is a TaxCalculator backed by a table of tax rates
+type RateTable []models.TaxRate
+
+// Calculate applies every rate matching the region and the line's tax class
+// to what is payable on the line after discounts
+func (t RateTable) Calculate(region string, line Line, mode string) ([]TaxLine, error) {
+	if mode != TaxExclusive && mode != TaxInclusive {
+		return nil, fmt.Errorf("unknown tax pricing mode %q", mode)
+	}
+
+	var rates []models.TaxRate
+	var combined float64
+	for _, rate := range t {
+		if rate.Region == region && rate.TaxClass == line.TaxClass {
+			rates = append(rates, rate)
+			combined += rate.Rate
+		}
+	}
+	if len(rates) == 0 || combined <= 0 {
+		return nil, nil
+	}
+
+	taxable := line.Total
+	totalTax := Round(taxable * combined / 100)
+	if mode == TaxInclusive {
+		totalTax = Round(taxable - taxable/(1+combined/100))
+		taxable = Round(taxable - totalTax)
+	}
+
+	// Split the combined tax across the individual rates
+	weights := make([]float64, len(rates))
+	for i, rate := range rates {
+		weights[i] = rate.Rate
+	}
+	amounts := spread(weights, totalTax)
+
+	taxes := make([]TaxLine, len(rates))
+	for i, rate := range rates {
+		taxes[i] = TaxLine{
+			Name:     rate.Name,
+			TaxClass: rate.TaxClass,
+			Rate:     rate.Rate,
+			Taxable:  taxable,
+			Amount:   amounts[i],
+		}
+	}
+	return taxes, nil
+}
+
+// ApplyTax taxes every line of the quote and builds the tax summary.
+// It should run after all discounts have been applied.
+func (q *Quote) ApplyTax(calc TaxCalculator, region, mode string) error {
+	q.TaxRegion = region
+	q.TaxMode = mode
+	q.Taxes = nil
+
+	for i := range q.Lines {
+		line := &q.Lines[i]
+		taxes, err := calc.Calculate(region, *line, mode)
+		if err != nil {
+			return err
+		}
+		line.Tax, line.TaxRate = 0, 0
+		for _, tax := range taxes {
+			line.Tax += tax.Amount
+			line.TaxRate += tax.Rate
+			q.addTaxSummary(tax)
+		}
+		line.Tax = Round(line.Tax)
+	}
+	q.recalculate()
+	return nil
+}
+
+// addTaxSummary adds a line tax to the summary entry with the same name, class and rate
+func (q *Quote) addTaxSummary(tax TaxLine) {
+	for i := range q.Taxes {
+		summary := &q.Taxes[i]
+		if summary.Name == tax.Name && summary.TaxClass == tax.TaxClass && summary.Rate == tax.Rate {
+			summary.Taxable = Round(summary.Taxable + tax.Taxable)
+			summary.Amount = Round(summary.Amount + tax.Amount)
+			return
+		}
+	}
+	q.Taxes = append(q.Taxes, tax)
+}
@@ -11,28 +11,129 @@ func RegisterRoutes(r *gin.Engine) {
 	// Public routes
 	r.POST("/users", controllers.Register)
 	r.POST("/users/login", controllers.Login)
+	r.POST("/users/login/2fa", controllers.LoginTwoFactor)
+	r.POST("/users/password/forgot", controllers.ForgotPassword)
+	r.POST("/users/password/reset", controllers.ResetPassword)
+	r.POST("/users/email/verify", controllers.VerifyEmail)
 	r.GET("/items", controllers.ListItems)
 	r.GET("/items/:id", controllers.GetItem)
+	r.GET("/items/:id/reviews", controllers.ListItemReviews)
+	r.GET("/wishlists/shared/:token", controllers.SharedWishlist)
+
+	// Payment gateway callbacks, authenticated by signature
+	r.POST("/payments/webhook", controllers.PaymentWebhook)
 
 	// Authenticated routes
 	auth := r.Group("/")
 	auth.Use(middleware.AuthMiddleware())
 	auth.GET("/users", controllers.ListUsers)
+	auth.GET("/users/me", controllers.GetProfile)
+	auth.PATCH("/users/me", controllers.UpdateProfile)
+	auth.POST("/users/me/password", controllers.ChangePassword)
+	auth.POST("/users/email/resend", controllers.ResendVerification)
+	auth.GET("/users/me/export", controllers.ExportAccount)
+	auth.GET("/users/me/erasure", controllers.GetErasureRequest)
+	auth.POST("/users/me/erasure", controllers.RequestErasure)
+	auth.DELETE("/users/me/erasure", controllers.CancelErasure)
+	auth.GET("/erasure-requests", controllers.ListErasureRequests)
+	auth.POST("/erasure-requests/:id/complete", controllers.CompleteErasure)
+	auth.POST("/users/:id/unlock", controllers.UnlockUser)
+	auth.GET("/users/:id", controllers.GetUser)
+	auth.POST("/users/:id/disable", controllers.DisableUser)
+	auth.POST("/users/:id/enable", controllers.EnableUser)
+	auth.PUT("/users/:id/role", controllers.SetUserRole)
+	auth.DELETE("/users/:id", controllers.DeleteUser)
+	auth.POST("/users/:id/impersonate", controllers.ImpersonateUser)
+	auth.DELETE("/impersonation", controllers.EndImpersonation)
+	auth.GET("/audit-logs", controllers.ListAuditLogs)
+
+	// Service accounts and API keys (admin only)
+	auth.GET("/service-accounts", controllers.ListServiceAccounts)
+	auth.POST("/service-accounts", controllers.CreateServiceAccount)
+	auth.POST("/service-accounts/:id/keys", controllers.CreateAPIKey)
+	auth.POST("/api-keys/:id/rotate", controllers.RotateAPIKey)
+	auth.DELETE("/api-keys/:id", controllers.RevokeAPIKey)
+
+	// Two-factor authentication
+	auth.GET("/users/2fa", controllers.TwoFactorStatus)
+	auth.POST("/users/2fa/enroll", controllers.EnrollTwoFactor)
+	auth.POST("/users/2fa/verify", controllers.VerifyTwoFactor)
+	auth.POST("/users/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+	auth.POST("/users/2fa/disable", controllers.DisableTwoFactor)
 
 	// Item management (admin only)
 	auth.POST("/items", controllers.CreateItem)
 	auth.PUT("/items/:id", controllers.UpdateItem)
 	auth.DELETE("/items/:id", controllers.DeleteItem)
 
+	// Reviews
+	auth.POST("/items/:id/reviews", controllers.CreateReview)
+	auth.GET("/reviews/moderation", controllers.ModerationQueue)
+	auth.PUT("/reviews/:id/moderate", controllers.ModerateReview)
+	auth.DELETE("/reviews/:id", controllers.DeleteReview)
+
+	// Retried requests with the same Idempotency-Key are answered once
+	idempotent := middleware.IdempotencyMiddleware()
+
 	// Cart management
-	auth.POST("/carts", controllers.AddToCart)
-	auth.PUT("/carts/:id", controllers.UpdateCartItem)
-	auth.DELETE("/carts/:id", controllers.RemoveCartItem)
+	auth.POST("/carts", idempotent, controllers.AddToCart)
+	auth.PUT("/carts/:id", idempotent, controllers.UpdateCartItem)
+	auth.DELETE("/carts/:id", idempotent, controllers.RemoveCartItem)
 	auth.GET("/carts", controllers.ListCarts)
+	auth.POST("/carts/coupon", idempotent, controllers.ApplyCoupon)
+	auth.POST("/carts/:id/save-for-later", controllers.SaveForLater)
+
+	// Wishlist
+	auth.GET("/wishlist", controllers.ListWishlist)
+	auth.POST("/wishlist", controllers.AddToWishlist)
+	auth.DELETE("/wishlist/:id", controllers.RemoveFromWishlist)
+	auth.POST("/wishlist/:id/move-to-cart", idempotent, controllers.MoveToCart)
+	auth.POST("/wishlist/share", controllers.ShareWishlist)
+	auth.DELETE("/wishlist/share", controllers.UnshareWishlist)
+
+	// Coupon management (admin only)
+	auth.POST("/coupons", controllers.CreateCoupon)
+	auth.GET("/coupons", controllers.ListCoupons)
+	auth.DELETE("/coupons/:id", controllers.DeleteCoupon)
+
+	// Promotion management (admin only)
+	auth.POST("/promotions", controllers.CreatePromotion)
+	auth.GET("/promotions", controllers.ListPromotions)
+	auth.DELETE("/promotions/:id", controllers.DeletePromotion)
+
+	// Tax rate management (admin only)
+	auth.POST("/tax-rates", controllers.CreateTaxRate)
+	auth.GET("/tax-rates", controllers.ListTaxRates)
+	auth.DELETE("/tax-rates/:id", controllers.DeleteTaxRate)
+
+	// Address book
+	auth.GET("/addresses", controllers.ListAddresses)
+	auth.POST("/addresses", controllers.CreateAddress)
+	auth.PUT("/addresses/:id", controllers.UpdateAddress)
+	auth.DELETE("/addresses/:id", controllers.DeleteAddress)
+
+	// Shipping
+	auth.GET("/shipping/quote", controllers.QuoteShipping)
+	auth.POST("/shipping-methods", controllers.CreateShippingMethod)
+	auth.GET("/shipping-methods", controllers.ListShippingMethods)
+	auth.DELETE("/shipping-methods/:id", controllers.DeleteShippingMethod)
 
 	// Order management
-	auth.POST("/orders", controllers.CreateOrder)
+	auth.POST("/orders", idempotent, controllers.CreateOrder)
 	auth.GET("/orders/user", controllers.UserOrders)
 	auth.GET("/orders/admin", controllers.AdminOrders)
+	auth.GET("/orders/:id", controllers.GetOrder)
 	auth.PUT("/orders/:id", controllers.UpdateOrderStatus)
+	auth.POST("/orders/:id/cancel", controllers.CancelOrder)
+	auth.GET("/orders/:id/invoice", controllers.GetInvoice)
+	auth.POST("/orders/:id/shipments", controllers.CreateShipment)
+	auth.POST("/orders/:id/reorder", controllers.Reorder)
+
+	// Returns
+	auth.POST("/orders/:id/returns", controllers.CreateReturn)
+	auth.GET("/returns", controllers.UserReturns)
+	auth.GET("/returns/admin", controllers.AdminReturns)
+	auth.PUT("/returns/:id/review", controllers.ReviewReturn)
+	auth.PUT("/returns/:id/receive", controllers.ReceiveReturn)
+	auth.POST("/returns/:id/refund", controllers.RefundReturn)
 }
@@ -11,7 +11,44 @@ function App() {
   const [cartCount, setCartCount] = useState(0);
   const [token, setToken] = useState(() => localStorage.getItem("token") || "");
   const [user, setUser] = useState(null);
-  const [page, setPage] = useState(() => (token ? "items" : "login"));
+  // A password reset link opens the reset form, even while signed in
+  const [resetToken, setResetToken] = useState(() =>
+    window.location.pathname === "/reset-password"
+      ? new URLSearchParams(window.location.search).get("token") || ""
+      : ""
+  );
+  const [page, setPage] = useState(() => (token && !resetToken ? "items" : "login"));
+  const [notice, setNotice] = useState(null);
+
+  // Verify the email address when opened from a verification link
+  useEffect(() => {
+    if (window.location.pathname !== "/verify-email") return;
+    const verifyToken = new URLSearchParams(window.location.search).get("token");
+    window.history.replaceState(null, "", "/");
+    if (!verifyToken) return;
+
+    fetch("https://abcdeventures.onrender.com/users/email/verify", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ token: verifyToken }),
+    })
+      .then(async (response) => {
+        const data = await response.json();
+        setNotice(
+          response.ok
+            ? { type: "success", text: "Your email address is verified." }
+            : { type: "error", text: data?.error || "Verification failed" }
+        );
+      })
+      .catch(() => setNotice({ type: "error", text: "Network error. Please try again." }));
+  }, []);
+
+  // Keep the reset token out of the address bar and history
+  useEffect(() => {
+    if (window.location.pathname === "/reset-password") {
+      window.history.replaceState(null, "", "/");
+    }
+  }, []);
 
   // Fetch cart count and user data when token changes
   useEffect(() => {
@@ -73,6 +110,13 @@ function App() {
     setPage("login");
   };
 
+  // A reset signs out every session, this one included
+  const handleResetDone = (reset) => {
+    setResetToken("");
+    if (token && reset) handleLogout();
+    else if (token) setPage("items");
+  };
+
   const handleRegisterSuccess = () => {
     setPage("login");
   };
@@ -141,8 +185,22 @@ function App() {
         )}
       </header>
 
+      {notice && (
+        <div
+          className={notice.type === "success" ? "form-success-banner" : "form-error-banner"}
+          onClick={() => setNotice(null)}
+        >
+          {notice.text}
+        </div>
+      )}
+
       {page === "login" && (
-        <Login onLogin={handleLogin} onRegister={() => setPage("register")} />
+        <Login
+          onLogin={handleLogin}
+          onRegister={() => setPage("register")}
+          resetToken={resetToken}
+          onResetDone={handleResetDone}
+        />
       )}
 
       {page === "register" && <Register onRegister={handleRegisterSuccess} />}
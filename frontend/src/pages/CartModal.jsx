@@ -1,5 +1,16 @@
 import React, { useEffect, useState } from "react";
 
+const emptyAddress = {
+  Name: "",
+  Line1: "",
+  Line2: "",
+  City: "",
+  Region: "",
+  PostalCode: "",
+  Country: "",
+  Phone: "",
+};
+
 const CartModal = ({ token, onClose, onAdd }) => {
   const [cartData, setCartData] = useState(null);
   const [loading, setLoading] = useState(true);
@@ -8,11 +19,24 @@ const CartModal = ({ token, onClose, onAdd }) => {
   const [removingItem, setRemovingItem] = useState(null);
   const [error, setError] = useState("");
   const [success, setSuccess] = useState("");
+  const [addresses, setAddresses] = useState([]);
+  const [addressId, setAddressId] = useState(null);
+  const [addingAddress, setAddingAddress] = useState(false);
+  const [address, setAddress] = useState(emptyAddress);
+  const [savingAddress, setSavingAddress] = useState(false);
+  const [methods, setMethods] = useState([]);
+  const [methodId, setMethodId] = useState(null);
 
   useEffect(() => {
     fetchCart();
+    fetchAddresses();
   }, [token]);
 
+  // Shipping depends on the address and on what is in the cart
+  useEffect(() => {
+    if (addressId && cartData?.items?.length) fetchQuote(addressId);
+  }, [addressId, cartData]);
+
   const fetchCart = async () => {
     try {
       const response = await fetch("https://abcdeventures.onrender.com/carts", {
@@ -27,6 +51,82 @@ const CartModal = ({ token, onClose, onAdd }) => {
     }
   };
 
+  const fetchAddresses = async () => {
+    try {
+      const response = await fetch("https://abcdeventures.onrender.com/addresses", {
+        headers: { Authorization: token },
+      });
+      const data = await response.json();
+      if (!response.ok) return;
+      setAddresses(data);
+      // The default address comes first
+      if (data.length > 0) {
+        setAddressId(data[0].ID);
+      } else {
+        setAddingAddress(true);
+      }
+    } catch {
+      setError("Failed to load addresses");
+    }
+  };
+
+  const fetchQuote = async (id) => {
+    try {
+      const response = await fetch(
+        `https://abcdeventures.onrender.com/shipping/quote?address_id=${id}`,
+        { headers: { Authorization: token } },
+      );
+      const data = await response.json();
+      if (response.ok) {
+        const options = data.methods || [];
+        setMethods(options);
+        setMethodId((current) =>
+          options.some((option) => option.MethodID === current)
+            ? current
+            : options[0]?.MethodID || null,
+        );
+      } else {
+        setMethods([]);
+        setMethodId(null);
+        setError(data?.error || "Failed to load shipping options");
+      }
+    } catch {
+      setError("Failed to load shipping options");
+    }
+  };
+
+  const saveAddress = async (e) => {
+    e.preventDefault();
+    setSavingAddress(true);
+    setError("");
+    try {
+      const response = await fetch("https://abcdeventures.onrender.com/addresses", {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: token,
+        },
+        // The first address becomes the default
+        body: JSON.stringify({ ...address, IsDefault: addresses.length === 0 }),
+      });
+
+      const data = await response.json();
+
+      if (response.ok) {
+        setAddresses([...addresses, data]);
+        setAddressId(data.ID);
+        setAddress(emptyAddress);
+        setAddingAddress(false);
+      } else {
+        setError(data?.error || "Failed to save address");
+      }
+    } catch {
+      setError("Network error. Please try again.");
+    } finally {
+      setSavingAddress(false);
+    }
+  };
+
   const updateQuantity = async (cartItemId, newQuantity) => {
     if (newQuantity < 1 || newQuantity > 100) return;
     setUpdatingQty(cartItemId);
@@ -82,7 +182,14 @@ const CartModal = ({ token, onClose, onAdd }) => {
     try {
       const response = await fetch("https://abcdeventures.onrender.com/orders", {
         method: "POST",
-        headers: { Authorization: token },
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: token,
+        },
+        body: JSON.stringify({
+          shipping_address_id: addressId,
+          shipping_method_id: methodId,
+        }),
       });
 
       const data = await response.json();
@@ -144,6 +251,8 @@ const CartModal = ({ token, onClose, onAdd }) => {
 
   const items = cartData?.items || [];
   const total = cartData?.total || 0;
+  const method = methods.find((option) => option.MethodID === methodId);
+  const shippingCost = method?.Cost || 0;
 
   return (
     <div className="modal-overlay" onClick={onClose}>
@@ -233,13 +342,126 @@ const CartModal = ({ token, onClose, onAdd }) => {
                 </div>
                 <div className="cart-summary-row">
                   <span>Shipping</span>
-                  <span style={{ color: "#10b981" }}>FREE</span>
+                  {!method ? (
+                    <span>—</span>
+                  ) : shippingCost === 0 ? (
+                    <span style={{ color: "#10b981" }}>FREE</span>
+                  ) : (
+                    <span>{formatPrice(shippingCost)}</span>
+                  )}
                 </div>
                 <div className="cart-summary-row">
                   <span>Total</span>
-                  <span>{formatPrice(total)}</span>
+                  <span>{formatPrice(total + shippingCost)}</span>
                 </div>
               </div>
+
+              <div style={{ marginTop: "24px" }}>
+                {addingAddress ? (
+                  <form onSubmit={saveAddress}>
+                    <h3 className="form-label" style={{ marginBottom: "12px" }}>
+                      Shipping address
+                    </h3>
+                    {[
+                      ["Name", "Full name", true],
+                      ["Line1", "Address line 1", true],
+                      ["Line2", "Address line 2", false],
+                      ["City", "City", true],
+                      ["Region", "State or region", false],
+                      ["PostalCode", "Postal code", false],
+                      ["Country", "Country code, e.g. US", true],
+                      ["Phone", "Phone", false],
+                    ].map(([field, placeholder, required]) => (
+                      <div className="form-group" key={field}>
+                        <input
+                          type="text"
+                          className="form-input"
+                          placeholder={placeholder}
+                          value={address[field]}
+                          onChange={(e) =>
+                            setAddress({ ...address, [field]: e.target.value })
+                          }
+                          required={required}
+                          maxLength={field === "Country" ? 2 : 100}
+                        />
+                      </div>
+                    ))}
+                    <div style={{ display: "flex", gap: "8px" }}>
+                      <button
+                        className="btn btn-primary"
+                        type="submit"
+                        disabled={savingAddress}
+                      >
+                        {savingAddress ? "Saving..." : "Save Address"}
+                      </button>
+                      {addresses.length > 0 && (
+                        <button
+                          className="btn btn-secondary"
+                          type="button"
+                          onClick={() => setAddingAddress(false)}
+                          disabled={savingAddress}
+                        >
+                          Cancel
+                        </button>
+                      )}
+                    </div>
+                  </form>
+                ) : (
+                  <>
+                    <div className="form-group">
+                      <label className="form-label" htmlFor="shippingAddress">
+                        Ship to
+                      </label>
+                      <div style={{ display: "flex", gap: "8px" }}>
+                        <select
+                          id="shippingAddress"
+                          className="form-input"
+                          value={addressId || ""}
+                          onChange={(e) => setAddressId(Number(e.target.value))}
+                        >
+                          {addresses.map((a) => (
+                            <option key={a.ID} value={a.ID}>
+                              {a.Name}, {a.Line1}, {a.City} {a.Country}
+                            </option>
+                          ))}
+                        </select>
+                        <button
+                          className="btn btn-secondary"
+                          type="button"
+                          onClick={() => setAddingAddress(true)}
+                        >
+                          New
+                        </button>
+                      </div>
+                    </div>
+
+                    <div className="form-group">
+                      <label className="form-label" htmlFor="shippingMethod">
+                        Shipping method
+                      </label>
+                      {methods.length === 0 ? (
+                        <p style={{ color: "#64748b", fontSize: "14px" }}>
+                          No shipping method delivers to this address
+                        </p>
+                      ) : (
+                        <select
+                          id="shippingMethod"
+                          className="form-input"
+                          value={methodId || ""}
+                          onChange={(e) => setMethodId(Number(e.target.value))}
+                        >
+                          {methods.map((option) => (
+                            <option key={option.MethodID} value={option.MethodID}>
+                              {option.Name} —{" "}
+                              {option.Cost === 0 ? "FREE" : formatPrice(option.Cost)}
+                            </option>
+                          ))}
+                        </select>
+                      )}
+                    </div>
+                  </>
+                )}
+              </div>
             </>
           )}
         </div>
@@ -252,7 +474,7 @@ const CartModal = ({ token, onClose, onAdd }) => {
             <button
               className="btn btn-success"
               onClick={checkout}
-              disabled={processing}
+              disabled={processing || !addressId || !methodId}
             >
               {processing ? "Processing..." : "Checkout →"}
             </button>
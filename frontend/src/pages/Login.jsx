@@ -1,11 +1,19 @@
 import React, { useState } from "react";
 
-const Login = ({ onLogin, onRegister }) => {
+const Login = ({ onLogin, onRegister, resetToken, onResetDone }) => {
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
   const [error, setError] = useState("");
   const [loading, setLoading] = useState(false);
   const [showPassword, setShowPassword] = useState(false);
+  const [challengeToken, setChallengeToken] = useState("");
+  const [code, setCode] = useState("");
+  const [forgot, setForgot] = useState(false);
+  const [email, setEmail] = useState("");
+  const [newPassword, setNewPassword] = useState("");
+  const [confirmPassword, setConfirmPassword] = useState("");
+  const [failures, setFailures] = useState([]);
+  const [success, setSuccess] = useState("");
 
   const handleLogin = async (e) => {
     e.preventDefault();
@@ -13,15 +21,26 @@ const Login = ({ onLogin, onRegister }) => {
     setLoading(true);
 
     try {
-      const response = await fetch("https://abcdeventures.onrender.com/users/login", {
-        method: "POST",
-        headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ username, password }),
-      });
+      // With two-factor authentication the password step returns a
+      // challenge that is answered with a code
+      const response = challengeToken
+        ? await fetch("https://abcdeventures.onrender.com/users/login/2fa", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ challenge_token: challengeToken, code }),
+          })
+        : await fetch("https://abcdeventures.onrender.com/users/login", {
+            method: "POST",
+            headers: { "Content-Type": "application/json" },
+            body: JSON.stringify({ username, password }),
+          });
 
       const data = await response.json();
 
-      if (response.ok && data.token) {
+      if (response.ok && data.two_factor_required) {
+        setChallengeToken(data.challenge_token);
+        setCode("");
+      } else if (response.ok && data.token) {
         // Store user info in localStorage
         localStorage.setItem("token", data.token);
         localStorage.setItem("user_id", data.user_id);
@@ -30,6 +49,77 @@ const Login = ({ onLogin, onRegister }) => {
         onLogin(data.token, data);
       } else {
         setError(data?.error || "Invalid username or password");
+        // An expired challenge or too many wrong codes means starting over
+        if (challengeToken && /log in again/.test(data?.error || "")) {
+          setChallengeToken("");
+        }
+      }
+    } catch {
+      setError("Network error. Please try again.");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // The answer is the same whether or not the email has an account
+  const handleForgot = async (e) => {
+    e.preventDefault();
+    setError("");
+    setSuccess("");
+    setLoading(true);
+
+    try {
+      const response = await fetch("https://abcdeventures.onrender.com/users/password/forgot", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ email }),
+      });
+
+      const data = await response.json();
+
+      if (response.ok) {
+        setSuccess(data.message);
+      } else {
+        setError(data?.error || "Failed to send reset link");
+      }
+    } catch {
+      setError("Network error. Please try again.");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // Sets the new password with the token from the mailed reset link
+  const handleReset = async (e) => {
+    e.preventDefault();
+    setError("");
+    setFailures([]);
+
+    if (newPassword !== confirmPassword) {
+      setError("Passwords do not match");
+      return;
+    }
+
+    setLoading(true);
+    try {
+      const response = await fetch("https://abcdeventures.onrender.com/users/password/reset", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ token: resetToken, password: newPassword }),
+      });
+
+      const data = await response.json();
+
+      if (response.ok) {
+        setSuccess(data.message);
+        setNewPassword("");
+        setConfirmPassword("");
+        onResetDone(true);
+      } else if (data?.failures?.length > 1) {
+        setError("Password does not meet all requirements");
+        setFailures(data.failures);
+      } else {
+        setError(data?.error || "Failed to reset password");
       }
     } catch {
       setError("Network error. Please try again.");
@@ -38,85 +128,281 @@ const Login = ({ onLogin, onRegister }) => {
     }
   };
 
+  const showLogin = () => {
+    setForgot(false);
+    setError("");
+    setSuccess("");
+  };
+
+  if (resetToken) {
+    return (
+      <div className="form-container">
+        <h2 className="form-title">Reset Password</h2>
+        <p className="form-subtitle">Choose a new password for your account</p>
+
+        {error && (
+          <div className="form-error-banner">
+            {error}
+            {failures.length > 0 && (
+              <ul style={{ margin: "8px 0 0", paddingLeft: "20px" }}>
+                {failures.map((failure) => (
+                  <li key={failure.rule}>{failure.message}</li>
+                ))}
+              </ul>
+            )}
+          </div>
+        )}
+
+        <form onSubmit={handleReset}>
+          <div className="form-group">
+            <label className="form-label" htmlFor="newPassword">
+              New password
+            </label>
+            <input
+              type="password"
+              id="newPassword"
+              className="form-input"
+              placeholder="Enter a new password"
+              value={newPassword}
+              onChange={(e) => setNewPassword(e.target.value)}
+              required
+              autoFocus
+              autoComplete="new-password"
+            />
+          </div>
+
+          <div className="form-group">
+            <label className="form-label" htmlFor="confirmPassword">
+              Confirm password
+            </label>
+            <input
+              type="password"
+              id="confirmPassword"
+              className="form-input"
+              placeholder="Enter the new password again"
+              value={confirmPassword}
+              onChange={(e) => setConfirmPassword(e.target.value)}
+              required
+              autoComplete="new-password"
+            />
+          </div>
+
+          <button
+            className="btn btn-primary"
+            type="submit"
+            style={{ width: "100%", marginTop: "8px" }}
+            disabled={loading}
+          >
+            {loading ? "Saving..." : "Set Password"}
+          </button>
+
+          <button
+            className="btn btn-secondary"
+            type="button"
+            style={{ width: "100%", marginTop: "12px" }}
+            onClick={() => onResetDone(false)}
+            disabled={loading}
+          >
+            Back to Sign In
+          </button>
+        </form>
+      </div>
+    );
+  }
+
+  if (forgot) {
+    return (
+      <div className="form-container">
+        <h2 className="form-title">Forgot Password</h2>
+        <p className="form-subtitle">We will email you a link to reset it</p>
+
+        {error && <div className="form-error-banner">{error}</div>}
+        {success && <div className="form-success-banner">{success}</div>}
+
+        <form onSubmit={handleForgot}>
+          <div className="form-group">
+            <label className="form-label" htmlFor="email">
+              Email
+            </label>
+            <input
+              type="email"
+              id="email"
+              className="form-input"
+              placeholder="Enter your email"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              required
+              autoFocus
+              autoComplete="email"
+            />
+          </div>
+
+          <button
+            className="btn btn-primary"
+            type="submit"
+            style={{ width: "100%", marginTop: "8px" }}
+            disabled={loading}
+          >
+            {loading ? "Sending..." : "Send Reset Link"}
+          </button>
+
+          <button
+            className="btn btn-secondary"
+            type="button"
+            style={{ width: "100%", marginTop: "12px" }}
+            onClick={showLogin}
+            disabled={loading}
+          >
+            Back to Sign In
+          </button>
+        </form>
+      </div>
+    );
+  }
+
   return (
     <div className="form-container">
       <h2 className="form-title">Welcome Back</h2>
       <p className="form-subtitle">Sign in to continue shopping</p>
 
       {error && <div className="form-error-banner">{error}</div>}
+      {success && <div className="form-success-banner">{success}</div>}
+
+      {challengeToken && (
+        <form onSubmit={handleLogin}>
+          <div className="form-group">
+            <label className="form-label" htmlFor="code">
+              Authentication code
+            </label>
+            <input
+              type="text"
+              id="code"
+              className="form-input"
+              placeholder="6-digit code or recovery code"
+              value={code}
+              onChange={(e) => setCode(e.target.value)}
+              required
+              autoFocus
+              autoComplete="one-time-code"
+            />
+          </div>
+
+          <button
+            className="btn btn-primary"
+            type="submit"
+            style={{ width: "100%", marginTop: "8px" }}
+            disabled={loading}
+          >
+            {loading ? "Verifying..." : "Verify"}
+          </button>
+
+          <button
+            className="btn btn-secondary"
+            type="button"
+            style={{ width: "100%", marginTop: "12px" }}
+            onClick={() => setChallengeToken("")}
+            disabled={loading}
+          >
+            Back
+          </button>
+        </form>
+      )}
 
-      <form onSubmit={handleLogin}>
-        <div className="form-group">
-          <label className="form-label" htmlFor="username">
-            Username
-          </label>
-          <input
-            type="text"
-            id="username"
-            className="form-input"
-            placeholder="Enter your username"
-            value={username}
-            onChange={(e) => setUsername(e.target.value)}
-            required
-            autoComplete="username"
-          />
-        </div>
-
-        <div className="form-group">
-          <label className="form-label" htmlFor="password">
-            Password
-          </label>
-          <div style={{ position: "relative" }}>
+      {!challengeToken && (
+        <form onSubmit={handleLogin}>
+          <div className="form-group">
+            <label className="form-label" htmlFor="username">
+              Username
+            </label>
             <input
-              type={showPassword ? "text" : "password"}
-              id="password"
+              type="text"
+              id="username"
               className="form-input"
-              placeholder="Enter your password"
-              value={password}
-              onChange={(e) => setPassword(e.target.value)}
+              placeholder="Enter your username"
+              value={username}
+              onChange={(e) => setUsername(e.target.value)}
               required
-              autoComplete="current-password"
-              style={{ paddingRight: "48px" }}
+              autoComplete="username"
             />
-            <button
-              type="button"
-              onClick={() => setShowPassword(!showPassword)}
-              style={{
-                position: "absolute",
-                right: "12px",
-                top: "50%",
-                transform: "translateY(-50%)",
-                background: "none",
-                border: "none",
-                cursor: "pointer",
-                color: "#64748b",
-                fontSize: "14px",
-              }}
-            >
-              {showPassword ? "Hide" : "Show"}
-            </button>
           </div>
-        </div>
-
-        <button
-          className="btn btn-primary"
-          type="submit"
-          style={{ width: "100%", marginTop: "8px" }}
-          disabled={loading}
-        >
-          {loading ? "Signing in..." : "Sign In"}
-        </button>
-
-        <button
-          className="btn btn-secondary"
-          type="button"
-          style={{ width: "100%", marginTop: "12px" }}
-          onClick={onRegister}
-          disabled={loading}
-        >
-          Create Account
-        </button>
-      </form>
+
+          <div className="form-group">
+            <label className="form-label" htmlFor="password">
+              Password
+            </label>
+            <div style={{ position: "relative" }}>
+              <input
+                type={showPassword ? "text" : "password"}
+                id="password"
+                className="form-input"
+                placeholder="Enter your password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                required
+                autoComplete="current-password"
+                style={{ paddingRight: "48px" }}
+              />
+              <button
+                type="button"
+                onClick={() => setShowPassword(!showPassword)}
+                style={{
+                  position: "absolute",
+                  right: "12px",
+                  top: "50%",
+                  transform: "translateY(-50%)",
+                  background: "none",
+                  border: "none",
+                  cursor: "pointer",
+                  color: "#64748b",
+                  fontSize: "14px",
+                }}
+              >
+                {showPassword ? "Hide" : "Show"}
+              </button>
+            </div>
+          </div>
+
+          <button
+            className="btn btn-primary"
+            type="submit"
+            style={{ width: "100%", marginTop: "8px" }}
+            disabled={loading}
+          >
+            {loading ? "Signing in..." : "Sign In"}
+          </button>
+
+          <button
+            className="btn btn-secondary"
+            type="button"
+            style={{ width: "100%", marginTop: "12px" }}
+            onClick={onRegister}
+            disabled={loading}
+          >
+            Create Account
+          </button>
+
+          <button
+            type="button"
+            onClick={() => {
+              setForgot(true);
+              setError("");
+              setSuccess("");
+            }}
+            disabled={loading}
+            style={{
+              display: "block",
+              margin: "16px auto 0",
+              background: "none",
+              border: "none",
+              cursor: "pointer",
+              color: "#64748b",
+              fontSize: "14px",
+            }}
+          >
+            Forgot password?
+          </button>
+        </form>
+      )}
 
       <div
         style={{
@@ -44,6 +44,7 @@ const OrderHistory = ({ token, onClose }) => {
     const statusColors = {
       pending: "pending",
       processing: "processing",
+      partially_shipped: "shipped",
       shipped: "shipped",
       completed: "completed",
       cancelled: "cancelled",
@@ -94,10 +95,10 @@ const OrderHistory = ({ token, onClose }) => {
           ) : (
             <div>
               {orders.map((order) => (
-                <div className="order-card" key={order.ID}>
+                <div className="order-card" key={order.Number}>
                   <div className="order-header">
                     <div>
-                      <span className="order-id">Order #{order.ID}</span>
+                      <span className="order-id">Order {order.Number}</span>
                       <span
                         style={{
                           marginLeft: "12px",
@@ -111,7 +112,7 @@ const OrderHistory = ({ token, onClose }) => {
                     <span
                       className={`order-status ${getStatusColor(order.Status)}`}
                     >
-                      {order.Status || "pending"}
+                      {(order.Status || "pending").replace("_", " ")}
                     </span>
                   </div>
                   <div className="order-body">
@@ -149,6 +150,18 @@ const OrderHistory = ({ token, onClose }) => {
                         <span>{formatPrice(order.Total)}</span>
                       </div>
                     )}
+                    {order.Shipments && order.Shipments.length > 0 && (
+                      <div className="order-shipments">
+                        {order.Shipments.map((shipment) => (
+                          <div className="order-shipment" key={shipment.ID}>
+                            📦{" "}
+                            {shipment.Carrier
+                              ? `${shipment.Carrier} · ${shipment.TrackingCode}`
+                              : "Shipped"}
+                          </div>
+                        ))}
+                      </div>
+                    )}
                   </div>
                 </div>
               ))}
@@ -2,9 +2,11 @@ import React, { useState, useCallback } from "react";
 
 const Register = ({ onRegister }) => {
   const [username, setUsername] = useState("");
+  const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
   const [confirmPassword, setConfirmPassword] = useState("");
   const [error, setError] = useState("");
+  const [failures, setFailures] = useState([]);
   const [success, setSuccess] = useState("");
   const [loading, setLoading] = useState(false);
   const [passwordStrength, setPasswordStrength] = useState(0);
@@ -15,7 +17,7 @@ const Register = ({ onRegister }) => {
     hasUppercase: /[A-Z]/.test(password),
     hasLowercase: /[a-z]/.test(password),
     hasNumber: /\d/.test(password),
-    hasSpecial: /[!@#$%^&*(),.?":{}|<>]/.test(password),
+    hasSpecial: /[^\p{L}\p{N}]/u.test(password),
   };
 
   // Calculate password strength
@@ -37,6 +39,7 @@ const Register = ({ onRegister }) => {
   const handleRegister = async (e) => {
     e.preventDefault();
     setError("");
+    setFailures([]);
     setSuccess("");
     setLoading(true);
 
@@ -59,18 +62,24 @@ const Register = ({ onRegister }) => {
       const response = await fetch("https://abcdeventures.onrender.com/users", {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ username, password }),
+        body: JSON.stringify({ username, email, password }),
       });
 
       const data = await response.json();
 
       if (response.ok) {
-        setSuccess("Registration successful! Redirecting to login...");
+        setSuccess("Registration successful! Check your email to verify your address. Redirecting to login...");
         setTimeout(() => {
           onRegister();
-        }, 1500);
+        }, 3000);
       } else {
-        setError(data?.error || "Registration failed");
+        // Password policy problems come back one per rule
+        if (data?.failures?.length > 1) {
+          setError("Password does not meet all requirements");
+          setFailures(data.failures);
+        } else {
+          setError(data?.error || "Registration failed");
+        }
       }
     } catch {
       setError("Network error. Please try again.");
@@ -92,7 +101,18 @@ const Register = ({ onRegister }) => {
       <h2 className="form-title">Create Account</h2>
       <p className="form-subtitle">Join us and start shopping today</p>
 
-      {error && <div className="form-error-banner">{error}</div>}
+      {error && (
+        <div className="form-error-banner">
+          {error}
+          {failures.length > 0 && (
+            <ul style={{ margin: "8px 0 0", paddingLeft: "20px" }}>
+              {failures.map((failure) => (
+                <li key={failure.rule}>{failure.message}</li>
+              ))}
+            </ul>
+          )}
+        </div>
+      )}
       {success && <div className="form-success-banner">{success}</div>}
 
       <form onSubmit={handleRegister}>
@@ -111,6 +131,20 @@ const Register = ({ onRegister }) => {
           />
         </div>
 
+        <div className="form-group">
+          <label className="form-label" htmlFor="email">Email</label>
+          <input
+            type="email"
+            id="email"
+            className="form-input"
+            placeholder="Enter your email"
+            value={email}
+            onChange={(e) => setEmail(e.target.value)}
+            required
+            maxLength={254}
+          />
+        </div>
+
         <div className="form-group">
           <label className="form-label" htmlFor="password">Password</label>
           <input